@@ -1,11 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	docs "github.com/PharmaKart/gateway-svc/docs"
+	"github.com/PharmaKart/gateway-svc/internal/audit"
+	"github.com/PharmaKart/gateway-svc/internal/auth"
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/handlers"
+	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/notify"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
 	"github.com/PharmaKart/gateway-svc/internal/routes"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/PharmaKart/gateway-svc/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -29,25 +43,118 @@ func SwaggerAuthMiddleware() gin.HandlerFunc {
 }
 
 func main() {
-	// Initialize logger
-	utils.InitLogger()
-
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Initialize logger
+	utils.InitLogger(cfg.Logging)
+
+	// Initialize tracing
+	shutdownTracer, err := utils.InitTracer(cfg.Tracing)
+	if err != nil {
+		utils.Logger.Fatal("Failed to initialize tracing", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	// Initialize audit log
+	auditSink, err := audit.NewSink(cfg.Audit)
+	if err != nil {
+		utils.Logger.Fatal("Failed to initialize audit log", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	// Initialize the email sender
+	sender, err := notify.NewSender(cfg.Notification)
+	if err != nil {
+		utils.Logger.Fatal("Failed to initialize notifications", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	// Initialize the blob stores for uploads
+	stores, err := storage.NewStores(cfg)
+	if err != nil {
+		utils.Logger.Fatal("Failed to initialize storage", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	// Scan uploads for malware before they are stored
+	scanner, err := scan.NewScanner(cfg.Scan)
+	if err != nil {
+		utils.Logger.Fatal("Failed to initialize malware scanning", map[string]interface{}{
+			"error": err,
+		})
+	}
+	guard := scan.NewGuard(scanner, stores.Quarantine, cfg.Scan.FailOpen)
+
+	// Sign receipts for files uploaded directly to storage
+	receipts := upload.NewReceipts(cfg.Upload.SigningKey, cfg.Upload.ReceiptTTL)
+
+	// Initialize a circuit breaker for every backend service
+	breakers, err := grpc.NewCircuitBreakers(cfg.CircuitBreaker, "auth", "product", "order", "payment", "reminder")
+	if err != nil {
+		utils.Logger.Fatal("Invalid circuit breaker configuration", map[string]interface{}{
+			"error": err,
+		})
+	}
+
 	// Initialize gRPC client for authentication service
-	authConn, err := grpc.NewClient(cfg.AuthServiceURL)
+	authConn, err := grpc.NewClient(cfg.AuthServiceURL, cfg.AuthServiceTLS, cfg.AuthServiceLB,
+		grpc.NewMetricsInterceptor("auth"),
+		breakers["auth"].Interceptor(),
+		grpc.NewPolicyInterceptor("auth", cfg.RPCPolicy),
+	)
 	if err != nil {
 		utils.Logger.Fatal("Failed to connect to authentication service", map[string]interface{}{
 			"error": err,
 		})
 	}
 
-	authClient := grpc.NewAuthServiceClient(authConn.Conn())
-	defer authConn.Close()
+	cachingAuthClient := grpc.NewCachingAuthClient(grpc.NewAuthServiceClient(authConn.Conn()), cfg.TokenCache)
+	var authClient grpc.AuthClient = cachingAuthClient
+
+	// Verify signed JWTs locally when configured
+	if cfg.JWT.Enabled {
+		jwtVerifier, err := auth.NewJWTVerifier(cfg.JWT)
+		if err != nil {
+			utils.Logger.Fatal("Failed to initialize JWT verification", map[string]interface{}{
+				"error": err,
+			})
+		}
+		defer jwtVerifier.Close()
+
+		authClient = grpc.NewLocalAuthClient(authClient, jwtVerifier)
+	}
+
+	// Load the RBAC policy, requiring a second factor for sessions of
+	// sensitive roles
+	enforcer, err := rbac.NewEnforcer(cfg.RBAC, cfg.MFA.RequiredRoles)
+	if err != nil {
+		utils.Logger.Fatal("Failed to load RBAC policy", map[string]interface{}{
+			"error": err,
+		})
+	}
+	defer enforcer.Close()
+
+	// Keep the list of revoked tokens in sync with the auth service
+	revocations, err := grpc.NewRevocationList(authClient, cfg.Revocation, cachingAuthClient)
+	if err != nil {
+		utils.Logger.Fatal("Invalid token revocation configuration", map[string]interface{}{
+			"error": err,
+		})
+	}
+	revocations.Start()
+	defer revocations.Stop()
 
 	// Initialize gRPC client for product service
-	productConn, err := grpc.NewClient(cfg.ProductServiceURL)
+	productConn, err := grpc.NewClient(cfg.ProductServiceURL, cfg.ProductServiceTLS, cfg.ProductServiceLB,
+		grpc.NewMetricsInterceptor("product"),
+		breakers["product"].Interceptor(),
+		grpc.NewPolicyInterceptor("product", cfg.RPCPolicy),
+	)
 	if err != nil {
 		utils.Logger.Fatal("Failed to connect to product service", map[string]interface{}{
 			"error": err,
@@ -55,10 +162,13 @@ func main() {
 	}
 
 	productClient := grpc.NewProductServiceClient(productConn.Conn())
-	defer productConn.Close()
 
 	// Initialize gRPC client for order service
-	orderConn, err := grpc.NewClient(cfg.OrderServiceURL)
+	orderConn, err := grpc.NewClient(cfg.OrderServiceURL, cfg.OrderServiceTLS, cfg.OrderServiceLB,
+		grpc.NewMetricsInterceptor("order"),
+		breakers["order"].Interceptor(),
+		grpc.NewPolicyInterceptor("order", cfg.RPCPolicy),
+	)
 	if err != nil {
 		utils.Logger.Fatal("Failed to connect to order service", map[string]interface{}{
 			"error": err,
@@ -66,10 +176,13 @@ func main() {
 	}
 
 	orderClient := grpc.NewOrderServiceClient(orderConn.Conn())
-	defer orderConn.Close()
 
 	// Initialize gRPC client for payment service
-	paymentConn, err := grpc.NewClient(cfg.PaymentServiceURL)
+	paymentConn, err := grpc.NewClient(cfg.PaymentServiceURL, cfg.PaymentServiceTLS, cfg.PaymentServiceLB,
+		grpc.NewMetricsInterceptor("payment"),
+		breakers["payment"].Interceptor(),
+		grpc.NewPolicyInterceptor("payment", cfg.RPCPolicy),
+	)
 	if err != nil {
 		utils.Logger.Fatal("Failed to connect to payment service", map[string]interface{}{
 			"error": err,
@@ -77,10 +190,13 @@ func main() {
 	}
 
 	paymentClient := grpc.NewPaymentServiceClient(paymentConn.Conn())
-	defer paymentConn.Close()
 
 	// Initialize gRPC client for reminder service
-	reminderConn, err := grpc.NewClient(cfg.ReminderServiceURL)
+	reminderConn, err := grpc.NewClient(cfg.ReminderServiceURL, cfg.ReminderServiceTLS, cfg.ReminderServiceLB,
+		grpc.NewMetricsInterceptor("reminder"),
+		breakers["reminder"].Interceptor(),
+		grpc.NewPolicyInterceptor("reminder", cfg.RPCPolicy),
+	)
 	if err != nil {
 		utils.Logger.Fatal("Failed to connect to reminder service", map[string]interface{}{
 			"error": err,
@@ -88,19 +204,42 @@ func main() {
 	}
 
 	reminderClient := grpc.NewReminderServiceClient(reminderConn.Conn())
-	defer reminderConn.Close()
 
 	// Set to Release mode once in production
 	gin.SetMode(gin.ReleaseMode)
 
-	// Initialize Gin router
-	r := gin.Default()
+	// Initialize Gin router. The default logger and recovery are replaced by
+	// the structured access log and a recovery that logs through utils.
+	r := gin.New()
+
+	// Client IPs feed rate limits and the access log, so X-Forwarded-For is
+	// only believed from the configured proxies
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		utils.Logger.Fatal("Invalid trusted proxies", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 
 	// Add Swagger documentation
 	docs.SwaggerInfo.Title = "PharmaKart Gateway API"
 	docs.SwaggerInfo.Version = "1.0"
 	docs.SwaggerInfo.Description = "This is the API documentation for the PharmaKart Gateway Service."
 
+	// Recover from panics, including those in the middleware below
+	r.Use(middleware.RecoveryMiddleware())
+
+	// Tag every request with a request ID
+	r.Use(middleware.RequestIDMiddleware())
+
+	// Trace every request
+	r.Use(middleware.TracingMiddleware(cfg.Tracing.ServiceName))
+
+	// Record request metrics
+	r.Use(middleware.MetricsMiddleware())
+
+	// Log every request
+	r.Use(middleware.AccessLogMiddleware())
+
 	// Set CORS headers
 	r.Use(utils.NewCors())
 
@@ -113,16 +252,104 @@ func main() {
 	r.GET("/swagger/*any", SwaggerAuthMiddleware(), ginSwagger.WrapHandler(
 		swaggerFiles.Handler,
 		ginSwagger.DefaultModelsExpandDepth(-1),
-	)) // Register auth routes
-	routes.RegisterRoutes(r, cfg, authClient, productClient, orderClient, paymentClient, reminderClient)
+	))
+
+	// Probe every backend for the readiness endpoint
+	healthChecker := grpc.NewHealthChecker(cfg.ReadinessTimeout,
+		grpc.Dependency{Name: "auth", Client: authConn, Critical: isCritical(cfg, "auth")},
+		grpc.Dependency{Name: "product", Client: productConn, Critical: isCritical(cfg, "product")},
+		grpc.Dependency{Name: "order", Client: orderConn, Critical: isCritical(cfg, "order")},
+		grpc.Dependency{Name: "payment", Client: paymentConn, Critical: isCritical(cfg, "payment")},
+		grpc.Dependency{Name: "reminder", Client: reminderConn, Critical: isCritical(cfg, "reminder")},
+	)
+
+	routes.RegisterRoutes(r, cfg, authClient, revocations, enforcer, productClient, orderClient, paymentClient, reminderClient, healthChecker, breakers, auditSink, sender, stores, guard, receipts)
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 
 	// Start server
-	utils.Info("Starting gateway service", map[string]interface{}{
-		"port": cfg.Port,
+	go func() {
+		utils.Info("Starting gateway service", map[string]interface{}{
+			"port": cfg.Port,
+		})
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.Logger.Fatal("Failed to start server", map[string]interface{}{
+				"error": err,
+			})
+		}
+	}()
+
+	// Wait for a termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+
+	shutdown(srv, cfg, shutdownTracer, auditSink, authConn, productConn, orderConn, paymentConn, reminderConn)
+}
+
+// isCritical reports whether the gateway cannot serve traffic without the
+// named backend service.
+func isCritical(cfg *config.Config, service string) bool {
+	for _, critical := range cfg.CriticalServices {
+		if critical == service {
+			return true
+		}
+	}
+	return false
+}
+
+// shutdown drains the HTTP server, closes the backend connections and the
+// audit log, and flushes pending spans.
+// Readiness is flipped first so the load balancer stops routing new requests
+// while in-flight ones are allowed to finish within cfg.ShutdownTimeout.
+func shutdown(srv *http.Server, cfg *config.Config, shutdownTracer func(context.Context) error, auditSink audit.Sink, conns ...grpc.GrpcClient) {
+	utils.Info("Shutting down gateway service", map[string]interface{}{
+		"delay":   cfg.ShutdownDelay.String(),
+		"timeout": cfg.ShutdownTimeout.String(),
 	})
-	if err := r.Run(":" + cfg.Port); err != nil {
-		utils.Logger.Fatal("Failed to start server", map[string]interface{}{
+
+	handlers.SetReady(false)
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	drained := true
+	if err := srv.Shutdown(ctx); err != nil {
+		drained = false
+		utils.Error("Failed to drain in-flight requests", map[string]interface{}{
 			"error": err,
 		})
 	}
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	// Requests still running may yet record events, so the audit log is
+	// left open when draining timed out; every event is synced as written.
+	if drained {
+		if err := auditSink.Close(); err != nil {
+			utils.Error("Failed to close audit log", map[string]interface{}{
+				"error": err,
+			})
+		}
+	}
+
+	// The drain may have used up ctx, so the spans get their own deadline
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), cfg.Tracing.FlushTimeout)
+	defer cancelFlush()
+
+	if err := shutdownTracer(flushCtx); err != nil {
+		utils.Error("Failed to flush traces", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	utils.Info("Gateway service stopped", nil)
 }
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// maxLineSize bounds a single event line when reading the log back.
+const maxLineSize = 1 << 20
+
+// FileSink appends events as JSON lines to a local file. The file is only
+// ever opened for appending; it is read back through separate handles, up to
+// the size written so far, so queries do not hold up writes.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	lastHash string
+	// size is how much of the file holds complete events.
+	size atomic.Int64
+}
+
+// NewFileSink opens, or creates, the audit log at path and resumes the chain
+// from its last event.
+func NewFileSink(path string) (*FileSink, error) {
+	sink := &FileSink{path: path}
+
+	if err := sink.scan(-1, func(event Event) error {
+		sink.lastHash = event.Hash
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	sink.file = file
+	sink.size.Store(info.Size())
+
+	return sink, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := event.seal(s.lastHash); err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	n, err := s.file.Write(append(data, '\n'))
+	s.size.Add(int64(n))
+	if err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	s.lastHash = event.Hash
+	return nil
+}
+
+func (s *FileSink) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	var events []Event
+	err := s.scan(s.size.Load(), func(event Event) error {
+		if filter.matches(event) {
+			events = append(events, event)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
+
+// Verify recomputes every hash and checks each event links to the previous.
+func (s *FileSink) Verify(ctx context.Context) error {
+	prevHash := ""
+	line := 0
+	return s.scan(s.size.Load(), func(event Event) error {
+		line++
+		if event.PrevHash != prevHash {
+			return fmt.Errorf("audit chain broken at line %d (event %s): previous hash mismatch", line, event.ID)
+		}
+		hash, err := event.computeHash()
+		if err != nil {
+			return err
+		}
+		if hash != event.Hash {
+			return fmt.Errorf("audit chain broken at line %d (event %s): event was modified", line, event.ID)
+		}
+		prevHash = event.Hash
+		return ctx.Err()
+	})
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// scan calls fn for every event in the first size bytes of the file, or in
+// all of it when size is negative, oldest first. A missing file holds no
+// events.
+func (s *FileSink) scan(size int64, fn func(Event) error) error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if size >= 0 {
+		r = io.LimitReader(file, size)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to parse audit log: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPSink posts every event as JSON to an external collector, which is
+// responsible for storing it. The chain restarts whenever the gateway does,
+// so the collector should verify each event against the previous one it
+// received from the same instance.
+type HTTPSink struct {
+	mu       sync.Mutex
+	url      string
+	client   *http.Client
+	lastHash string
+}
+
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, event *Event) error {
+	// Events are posted in order so the collector sees an unbroken chain.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := event.seal(s.lastHash); err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send audit event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit collector returned %s", resp.Status)
+	}
+
+	s.lastHash = event.Hash
+	return nil
+}
+
+func (s *HTTPSink) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	return nil, ErrQueryNotSupported
+}
+
+func (s *HTTPSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
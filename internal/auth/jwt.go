@@ -0,0 +1,248 @@
+// Package auth verifies signed JWTs locally, so authenticated requests do
+// not depend on the auth service being reachable.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// minForcedRefreshInterval limits how often an unknown kid can trigger an
+// out-of-band key refresh, so forged tokens cannot hammer the JWKS endpoint.
+const minForcedRefreshInterval = 30 * time.Second
+
+// ErrNotJWT is returned for tokens that are not JWTs, which must be verified
+// by the auth service instead.
+var ErrNotJWT = errors.New("token is not a JWT")
+
+// validMethods are the asymmetric algorithms accepted for local verification.
+var validMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Claims are the identity claims the gateway needs from a token.
+type Claims struct {
+	UserID string
+	Role   string
+	// ID, IssuedAt and ExpiresAt identify the token for revocation checks.
+	ID        string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// MFA is set when the session was established with a second factor.
+	MFA bool
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+	MFA    bool   `json:"mfa"`
+	// AMR lists the authentication methods (RFC 8176), e.g. ["pwd", "otp"].
+	AMR []string `json:"amr"`
+}
+
+// usedMFA reports whether the token claims a second factor, either with the
+// mfa claim or with an "mfa" or "otp" authentication method.
+func (c *tokenClaims) usedMFA() bool {
+	if c.MFA {
+		return true
+	}
+	for _, method := range c.AMR {
+		if method == "mfa" || method == "otp" {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTVerifier validates JWT signatures against a key set loaded from a JWKS
+// endpoint or a key file and refreshed periodically, selecting the key by the
+// token's kid header so keys can be rotated without a restart.
+type JWTVerifier struct {
+	cfg    config.JWTConfig
+	source keySource
+	parser *jwt.Parser
+
+	// forced shares one out-of-band refresh between concurrent requests
+	// carrying unknown kids.
+	forced singleflight.Group
+
+	mu            sync.RWMutex
+	keys          keySet
+	lastRefreshAt time.Time
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewJWTVerifier loads the initial key set and starts refreshing it.
+func NewJWTVerifier(cfg config.JWTConfig) (*JWTVerifier, error) {
+	source, err := newKeySource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	v := &JWTVerifier{
+		cfg:    cfg,
+		source: source,
+		parser: jwt.NewParser(opts...),
+		done:   make(chan struct{}),
+	}
+	if err := v.refresh(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if cfg.RefreshInterval > 0 {
+		go v.watch()
+	}
+
+	return v, nil
+}
+
+// Verify checks the token's signature, exp, nbf, iss and aud and returns its
+// claims. It returns ErrNotJWT for opaque tokens.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrNotJWT
+	}
+
+	var claims tokenClaims
+	_, err := v.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	})
+	if errors.Is(err, jwt.ErrTokenMalformed) {
+		return nil, ErrNotJWT
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userID := claims.UserID
+	if userID == "" {
+		userID = claims.Subject
+	}
+	if userID == "" || claims.Role == "" {
+		return nil, errors.New("token is missing the user_id or role claim")
+	}
+
+	result := &Claims{
+		UserID: userID,
+		Role:   claims.Role,
+		ID:     claims.ID,
+		MFA:    claims.usedMFA(),
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return result, nil
+}
+
+// Close stops the background refresh.
+func (v *JWTVerifier) Close() {
+	v.once.Do(func() { close(v.done) })
+}
+
+// key returns the public key for kid, refreshing the key set once if the kid
+// is unknown, which is how newly rotated keys are picked up early.
+func (v *JWTVerifier) key(ctx context.Context, kid string) (interface{}, error) {
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+
+	// The shared refresh must not be cancelled by whichever caller started it.
+	result := v.forced.DoChan("", func() (interface{}, error) {
+		return nil, v.forceRefresh(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			utils.WarnContext(ctx, "Failed to refresh JWT signing keys", map[string]interface{}{
+				"error": res.Err,
+			})
+		}
+	}
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// forceRefresh refreshes the key set unless it was refreshed less than
+// minForcedRefreshInterval ago. The check is made inside the shared call,
+// so callers that saw an unknown kid just before a refresh finished do not
+// start another one.
+func (v *JWTVerifier) forceRefresh(ctx context.Context) error {
+	v.mu.RLock()
+	recent := time.Since(v.lastRefreshAt) < minForcedRefreshInterval
+	v.mu.RUnlock()
+	if recent {
+		return nil
+	}
+	return v.refresh(ctx)
+}
+
+func (v *JWTVerifier) lookup(kid string) (interface{}, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.keys.lookup(kid)
+}
+
+func (v *JWTVerifier) refresh(ctx context.Context) error {
+	keys, err := v.source.load(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.lastRefreshAt = time.Now()
+	if err != nil {
+		return err
+	}
+	v.keys = keys
+	return nil
+}
+
+func (v *JWTVerifier) watch() {
+	ticker := time.NewTicker(v.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-ticker.C:
+			if err := v.refresh(context.Background()); err != nil {
+				// Keep verifying with the previous keys
+				utils.Error("Failed to refresh JWT signing keys", map[string]interface{}{
+					"error": err,
+				})
+			}
+		}
+	}
+}
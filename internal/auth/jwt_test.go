@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func init() {
+	utils.InitLogger(config.LoggingConfig{Level: "fatal"})
+}
+
+// jwksServer serves the public halves of its signing keys as a JWKS and
+// counts how often it was fetched.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]ed25519.PrivateKey
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: make(map[string]ed25519.PrivateKey)}
+	for _, kid := range kids {
+		s.addKey(t, kid)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var doc struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+			doc.Keys = append(doc.Keys, jwk{Kty: "OKP", Crv: "Ed25519", Kid: kid, Use: "sig", X: x})
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+}
+
+// sign returns a token signed with the key kid, which need not be served.
+func (s *jwksServer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	s.mu.Unlock()
+	if !ok {
+		_, key, _ = ed25519.GenerateKey(nil)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestVerifier(t *testing.T, server *jwksServer) *JWTVerifier {
+	t.Helper()
+
+	v, err := NewJWTVerifier(config.JWTConfig{Enabled: true, JWKSURL: server.URL, Leeway: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(v.Close)
+	return v
+}
+
+// allowForcedRefresh pretends the keys were last refreshed long ago.
+func allowForcedRefresh(v *JWTVerifier) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastRefreshAt = time.Time{}
+}
+
+func userClaims(exp time.Time) jwt.MapClaims {
+	return jwt.MapClaims{"user_id": "u1", "role": "customer", "jti": "t1", "exp": exp.Unix(), "amr": []string{"pwd", "otp"}}
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	server := newJWKSServer(t, "k1")
+	v := newTestVerifier(t, server)
+	now := time.Now()
+
+	// HS256 signed with the public key as the secret, the classic key
+	// confusion attack.
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims(now.Add(time.Hour)))
+	hs256.Header["kid"] = "k1"
+	hmacToken, err := hs256.SignedString([]byte(server.keys["k1"].Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, userClaims(now.Add(time.Hour)))
+	none.Header["kid"] = "k1"
+	noneToken, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noExpiry := userClaims(now)
+	delete(noExpiry, "exp")
+	noRole := userClaims(now.Add(time.Hour))
+	delete(noRole, "role")
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "valid", token: server.sign(t, "k1", userClaims(now.Add(time.Hour)))},
+		{name: "expired within the leeway", token: server.sign(t, "k1", userClaims(now.Add(-10*time.Second)))},
+		{name: "expired", token: server.sign(t, "k1", userClaims(now.Add(-time.Minute))), want: jwt.ErrTokenExpired},
+		{name: "not valid yet", token: server.sign(t, "k1", jwt.MapClaims{"user_id": "u1", "role": "customer", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}), want: jwt.ErrTokenNotValidYet},
+		{name: "no expiry", token: server.sign(t, "k1", noExpiry), want: jwt.ErrTokenRequiredClaimMissing},
+		{name: "HS256", token: hmacToken, want: jwt.ErrTokenSignatureInvalid},
+		{name: "none", token: noneToken, want: jwt.ErrTokenSignatureInvalid},
+		{name: "other key", token: server.sign(t, "k2", userClaims(now.Add(time.Hour))), want: jwt.ErrTokenUnverifiable},
+		{name: "no role", token: server.sign(t, "k1", noRole), want: errors.New("missing the user_id or role claim")},
+		{name: "opaque", token: "opaque-session-token", want: ErrNotJWT},
+	}
+
+	for _, tt := range tests {
+		claims, err := v.Verify(context.Background(), tt.token)
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			} else if claims.UserID != "u1" || claims.Role != "customer" || claims.ID != "t1" || !claims.MFA {
+				t.Errorf("%s: got %+v", tt.name, claims)
+			}
+			continue
+		}
+		if err == nil || !errors.Is(err, tt.want) && !strings.Contains(err.Error(), tt.want.Error()) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestJWTVerifierRefreshesOnUnknownKid(t *testing.T) {
+	server := newJWKSServer(t, "k1")
+	v := newTestVerifier(t, server)
+
+	// A key rotated in after startup is picked up on first use.
+	server.addKey(t, "k2")
+	allowForcedRefresh(v)
+	if _, err := v.Verify(context.Background(), server.sign(t, "k2", userClaims(time.Now().Add(time.Hour)))); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("got %d fetches, want 2", fetches)
+	}
+
+	// Within the refresh interval an unknown kid fetches nothing.
+	server.addKey(t, "k3")
+	if _, err := v.Verify(context.Background(), server.sign(t, "k3", userClaims(time.Now().Add(time.Hour)))); err == nil {
+		t.Error("got no error for a kid seen before the next refresh")
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("got %d fetches, want 2", fetches)
+	}
+}
+
+func TestJWTVerifierSharesForcedRefresh(t *testing.T) {
+	server := newJWKSServer(t, "k1")
+	v := newTestVerifier(t, server)
+	allowForcedRefresh(v)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token := server.sign(t, "forged", userClaims(time.Now().Add(time.Hour)))
+			if _, err := v.Verify(context.Background(), token); err == nil {
+				t.Error("forged token accepted")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("got %d fetches for 50 tokens with an unknown kid, want 2", fetches)
+	}
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxJWKSSize bounds the JWKS document read from the network.
+const maxJWKSSize = 1 << 20
+
+// keySet maps kid to public key. A key loaded from a PEM file has no kid and
+// is stored under "", where it matches every token.
+type keySet map[string]interface{}
+
+func (k keySet) lookup(kid string) (interface{}, bool) {
+	if key, ok := k[kid]; ok {
+		return key, true
+	}
+	key, ok := k[""]
+	return key, ok
+}
+
+type keySource interface {
+	load(ctx context.Context) (keySet, error)
+}
+
+func newKeySource(cfg config.JWTConfig) (keySource, error) {
+	switch {
+	case cfg.JWKSURL != "":
+		return &jwksURLSource{url: cfg.JWKSURL, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case cfg.KeyFile != "":
+		return &keyFileSource{path: cfg.KeyFile}, nil
+	default:
+		return nil, errors.New("local JWT verification needs JWT_JWKS_URL or JWT_KEY_FILE")
+	}
+}
+
+// jwksURLSource fetches a JWKS document over HTTP.
+type jwksURLSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s *jwksURLSource) load(ctx context.Context) (keySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	return parseJWKS(data)
+}
+
+// keyFileSource reads a JWKS document or a PEM encoded public key from disk.
+type keyFileSource struct {
+	path string
+}
+
+func (s *keyFileSource) load(ctx context.Context) (keySet, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file: %w", err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := parsePEMPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return keySet{"": key}, nil
+	}
+	return parseJWKS(data)
+}
+
+func parsePEMPublicKey(data []byte) (interface{}, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("JWT key file holds no supported public key")
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS parses the signing keys of a JWKS document. Keys of unsupported
+// types are skipped so one new key type does not break verification.
+func parseJWKS(data []byte) (keySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(keySet)
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS holds no supported signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -11,6 +11,17 @@ type AuthClient interface {
 	Register(ctx context.Context, req *proto.RegisterRequest) (*proto.RegisterResponse, error)
 	Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error)
 	VerifyToken(ctx context.Context, req *proto.VerifyTokenRequest) (*proto.VerifyTokenResponse, error)
+	RefreshToken(ctx context.Context, req *proto.RefreshTokenRequest) (*proto.RefreshTokenResponse, error)
+	Logout(ctx context.Context, req *proto.LogoutRequest) (*proto.LogoutResponse, error)
+	ListRevokedTokens(ctx context.Context, req *proto.ListRevokedTokensRequest) (*proto.ListRevokedTokensResponse, error)
+	CreateAccountToken(ctx context.Context, req *proto.CreateAccountTokenRequest) (*proto.CreateAccountTokenResponse, error)
+	VerifyEmail(ctx context.Context, req *proto.VerifyEmailRequest) (*proto.VerifyEmailResponse, error)
+	ResetPassword(ctx context.Context, req *proto.ResetPasswordRequest) (*proto.ResetPasswordResponse, error)
+	VerifyMFA(ctx context.Context, req *proto.VerifyMFARequest) (*proto.VerifyMFAResponse, error)
+	EnrollMFA(ctx context.Context, req *proto.EnrollMFARequest) (*proto.EnrollMFAResponse, error)
+	ConfirmMFA(ctx context.Context, req *proto.ConfirmMFARequest) (*proto.ConfirmMFAResponse, error)
+	DisableMFA(ctx context.Context, req *proto.DisableMFARequest) (*proto.DisableMFAResponse, error)
+	RegenerateRecoveryCodes(ctx context.Context, req *proto.RegenerateRecoveryCodesRequest) (*proto.RegenerateRecoveryCodesResponse, error)
 }
 
 type authClient struct {
@@ -34,3 +45,47 @@ func (c *authClient) Login(ctx context.Context, req *proto.LoginRequest) (*proto
 func (c *authClient) VerifyToken(ctx context.Context, req *proto.VerifyTokenRequest) (*proto.VerifyTokenResponse, error) {
 	return c.client.VerifyToken(ctx, req)
 }
+
+func (c *authClient) RefreshToken(ctx context.Context, req *proto.RefreshTokenRequest) (*proto.RefreshTokenResponse, error) {
+	return c.client.RefreshToken(ctx, req)
+}
+
+func (c *authClient) Logout(ctx context.Context, req *proto.LogoutRequest) (*proto.LogoutResponse, error) {
+	return c.client.Logout(ctx, req)
+}
+
+func (c *authClient) ListRevokedTokens(ctx context.Context, req *proto.ListRevokedTokensRequest) (*proto.ListRevokedTokensResponse, error) {
+	return c.client.ListRevokedTokens(ctx, req)
+}
+
+func (c *authClient) CreateAccountToken(ctx context.Context, req *proto.CreateAccountTokenRequest) (*proto.CreateAccountTokenResponse, error) {
+	return c.client.CreateAccountToken(ctx, req)
+}
+
+func (c *authClient) VerifyEmail(ctx context.Context, req *proto.VerifyEmailRequest) (*proto.VerifyEmailResponse, error) {
+	return c.client.VerifyEmail(ctx, req)
+}
+
+func (c *authClient) ResetPassword(ctx context.Context, req *proto.ResetPasswordRequest) (*proto.ResetPasswordResponse, error) {
+	return c.client.ResetPassword(ctx, req)
+}
+
+func (c *authClient) VerifyMFA(ctx context.Context, req *proto.VerifyMFARequest) (*proto.VerifyMFAResponse, error) {
+	return c.client.VerifyMFA(ctx, req)
+}
+
+func (c *authClient) EnrollMFA(ctx context.Context, req *proto.EnrollMFARequest) (*proto.EnrollMFAResponse, error) {
+	return c.client.EnrollMFA(ctx, req)
+}
+
+func (c *authClient) ConfirmMFA(ctx context.Context, req *proto.ConfirmMFARequest) (*proto.ConfirmMFAResponse, error) {
+	return c.client.ConfirmMFA(ctx, req)
+}
+
+func (c *authClient) DisableMFA(ctx context.Context, req *proto.DisableMFARequest) (*proto.DisableMFAResponse, error) {
+	return c.client.DisableMFA(ctx, req)
+}
+
+func (c *authClient) RegenerateRecoveryCodes(ctx context.Context, req *proto.RegenerateRecoveryCodesRequest) (*proto.RegenerateRecoveryCodesResponse, error) {
+	return c.client.RegenerateRecoveryCodes(ctx, req)
+}
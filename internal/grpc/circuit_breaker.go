@@ -0,0 +1,302 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreakerState is a point-in-time view of a circuit breaker.
+type CircuitBreakerState struct {
+	Service      string    `json:"service"`
+	State        string    `json:"state"`
+	FailureRate  float64   `json:"failure_rate"`
+	SlowCallRate float64   `json:"slow_call_rate"`
+	Calls        int       `json:"calls"`
+	OpenedAt     time.Time `json:"opened_at,omitempty"`
+	RetryAfter   int       `json:"retry_after_seconds,omitempty"`
+}
+
+// CircuitBreaker tracks the outcome of the most recent calls to a backend
+// service and stops sending it traffic once too many of them fail or are
+// slow. After OpenTimeout it lets a few probe calls through and closes again
+// only if all of them succeed.
+type CircuitBreaker struct {
+	service string
+	cfg     config.CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	openedAt time.Time
+	outcomes []outcome
+	next     int
+	probes   int
+	passed   int
+}
+
+type outcome struct {
+	failed bool
+	slow   bool
+}
+
+// CircuitBreakers holds the circuit breaker of every backend, keyed by
+// service name.
+type CircuitBreakers map[string]*CircuitBreaker
+
+func NewCircuitBreaker(service string, cfg config.CircuitBreakerConfig) (*CircuitBreaker, error) {
+	if err := validateCircuitBreakerConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return &CircuitBreaker{
+		service:  service,
+		cfg:      cfg,
+		state:    CircuitClosed,
+		outcomes: make([]outcome, 0, cfg.WindowSize),
+	}, nil
+}
+
+// NewCircuitBreakers creates a circuit breaker for each of the services.
+func NewCircuitBreakers(cfg config.CircuitBreakerConfig, services ...string) (CircuitBreakers, error) {
+	breakers := make(CircuitBreakers, len(services))
+	for _, service := range services {
+		breaker, err := NewCircuitBreaker(service, cfg)
+		if err != nil {
+			return nil, err
+		}
+		breakers[service] = breaker
+	}
+	return breakers, nil
+}
+
+// validateCircuitBreakerConfig rejects settings the breaker cannot work
+// with, such as an empty window, rather than failing on the first call.
+func validateCircuitBreakerConfig(cfg config.CircuitBreakerConfig) error {
+	switch {
+	case cfg.WindowSize < 1:
+		return fmt.Errorf("CIRCUIT_BREAKER_WINDOW_SIZE must be at least 1, got %d", cfg.WindowSize)
+	case cfg.MinRequests < 1 || cfg.MinRequests > cfg.WindowSize:
+		return fmt.Errorf("CIRCUIT_BREAKER_MIN_REQUESTS must be between 1 and the window size %d, got %d", cfg.WindowSize, cfg.MinRequests)
+	case cfg.FailureRateThreshold <= 0 || cfg.FailureRateThreshold > 1:
+		return fmt.Errorf("CIRCUIT_BREAKER_FAILURE_RATE must be above 0 and at most 1, got %v", cfg.FailureRateThreshold)
+	case cfg.SlowCallRateThreshold <= 0 || cfg.SlowCallRateThreshold > 1:
+		return fmt.Errorf("CIRCUIT_BREAKER_SLOW_CALL_RATE must be above 0 and at most 1, got %v", cfg.SlowCallRateThreshold)
+	case cfg.SlowCallThreshold <= 0:
+		return fmt.Errorf("CIRCUIT_BREAKER_SLOW_CALL_THRESHOLD must be positive, got %s", cfg.SlowCallThreshold)
+	case cfg.OpenTimeout <= 0:
+		return fmt.Errorf("CIRCUIT_BREAKER_OPEN_TIMEOUT must be positive, got %s", cfg.OpenTimeout)
+	case cfg.HalfOpenProbes < 1:
+		return fmt.Errorf("CIRCUIT_BREAKER_HALF_OPEN_PROBES must be at least 1, got %d", cfg.HalfOpenProbes)
+	}
+	return nil
+}
+
+// Interceptor returns a unary client interceptor that fails fast with
+// codes.Unavailable while the circuit is open.
+func (b *CircuitBreaker) Interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !b.cfg.Enabled {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		probe, err := b.allow()
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		b.record(probe, isBackendFailure(err), time.Since(start) >= b.cfg.SlowCallThreshold)
+
+		return err
+	}
+}
+
+// IsOpen reports whether calls to the service are currently being rejected.
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == CircuitOpen && time.Since(b.openedAt) < b.cfg.OpenTimeout
+}
+
+// RetryAfter returns how long until the circuit lets probe traffic through.
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.retryAfter()
+}
+
+func (b *CircuitBreaker) Service() string {
+	return b.service
+}
+
+// State returns a snapshot of the circuit breaker.
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failureRate, slowRate := b.rates()
+	state := CircuitBreakerState{
+		Service:      b.service,
+		State:        b.state,
+		FailureRate:  failureRate,
+		SlowCallRate: slowRate,
+		Calls:        len(b.outcomes),
+	}
+	if b.state != CircuitClosed {
+		state.OpenedAt = b.openedAt
+		state.RetryAfter = int(b.retryAfter().Round(time.Second).Seconds())
+	}
+	return state
+}
+
+func (b *CircuitBreaker) retryAfter() time.Duration {
+	if b.state == CircuitClosed {
+		return 0
+	}
+	remaining := b.cfg.OpenTimeout - time.Since(b.openedAt)
+	if remaining < time.Second {
+		return time.Second
+	}
+	return remaining
+}
+
+func (b *CircuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		b.transition(CircuitHalfOpen)
+	}
+
+	switch b.state {
+	case CircuitOpen:
+		return false, b.unavailable()
+	case CircuitHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return false, b.unavailable()
+		}
+		b.probes++
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (b *CircuitBreaker) record(probe, failed, slow bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		if b.state != CircuitHalfOpen {
+			return
+		}
+		if failed {
+			b.transition(CircuitOpen)
+			return
+		}
+		b.passed++
+		if b.passed >= b.cfg.HalfOpenProbes {
+			b.transition(CircuitClosed)
+		}
+		return
+	}
+
+	if b.state != CircuitClosed {
+		return
+	}
+
+	o := outcome{failed: failed, slow: slow}
+	if len(b.outcomes) < b.cfg.WindowSize {
+		b.outcomes = append(b.outcomes, o)
+	} else {
+		b.outcomes[b.next] = o
+		b.next = (b.next + 1) % b.cfg.WindowSize
+	}
+
+	if len(b.outcomes) < b.cfg.MinRequests {
+		return
+	}
+
+	failureRate, slowRate := b.rates()
+	if failureRate >= b.cfg.FailureRateThreshold || slowRate >= b.cfg.SlowCallRateThreshold {
+		b.transition(CircuitOpen)
+	}
+}
+
+func (b *CircuitBreaker) rates() (float64, float64) {
+	if len(b.outcomes) == 0 {
+		return 0, 0
+	}
+
+	var failed, slow int
+	for _, o := range b.outcomes {
+		if o.failed {
+			failed++
+		}
+		if o.slow {
+			slow++
+		}
+	}
+	total := float64(len(b.outcomes))
+	return float64(failed) / total, float64(slow) / total
+}
+
+func (b *CircuitBreaker) transition(state string) {
+	failureRate, slowRate := b.rates()
+	utils.Warn("Circuit breaker state changed", map[string]interface{}{
+		"service":        b.service,
+		"from":           b.state,
+		"to":             state,
+		"failure_rate":   failureRate,
+		"slow_call_rate": slowRate,
+	})
+
+	b.state = state
+	b.probes = 0
+	b.passed = 0
+
+	switch state {
+	case CircuitOpen:
+		b.openedAt = time.Now()
+	case CircuitClosed:
+		b.outcomes = b.outcomes[:0]
+		b.next = 0
+	}
+}
+
+func (b *CircuitBreaker) unavailable() error {
+	st := status.New(codes.Unavailable, "circuit breaker is open for "+b.service+" service")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(b.retryAfter()),
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// isBackendFailure reports whether err indicates the backend itself is
+// unhealthy, as opposed to a request the backend rejected.
+func isBackendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"google.golang.org/grpc/credentials"
+)
+
+// reloadingCredentials is a TLS transport credential that rebuilds its
+// tls.Config whenever the CA bundle or client key pair changes on disk, so
+// rotated certificates are picked up by new connections without a restart.
+type reloadingCredentials struct {
+	cfg config.TLSConfig
+
+	mu       sync.RWMutex
+	tlsCfg   *tls.Config
+	modTimes map[string]time.Time
+
+	stop chan struct{}
+}
+
+func newReloadingCredentials(cfg config.TLSConfig) (*reloadingCredentials, error) {
+	c := &reloadingCredentials{
+		cfg:  cfg,
+		stop: make(chan struct{}),
+	}
+
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	if cfg.ReloadInterval > 0 {
+		go c.watch()
+	}
+
+	return c, nil
+}
+
+func (c *reloadingCredentials) files() []string {
+	var files []string
+	for _, f := range []string{c.cfg.CAFile, c.cfg.CertFile, c.cfg.KeyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (c *reloadingCredentials) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes[f] = info.ModTime()
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.cfg.CAFile != "" {
+		pem, err := os.ReadFile(c.cfg.CAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", c.cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if c.cfg.CertFile != "" || c.cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// The server name may be overridden concurrently, so read it under the lock
+	c.mu.Lock()
+	tlsCfg.ServerName = c.cfg.ServerName
+	c.tlsCfg = tlsCfg
+	c.modTimes = modTimes
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *reloadingCredentials) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			// A rotation in progress may briefly remove the file.
+			continue
+		}
+		if !info.ModTime().Equal(c.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *reloadingCredentials) watch() {
+	ticker := time.NewTicker(c.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if !c.changed() {
+				continue
+			}
+			if err := c.reload(); err != nil {
+				utils.Error("Failed to reload TLS certificates", map[string]interface{}{
+					"error": err,
+				})
+				continue
+			}
+			utils.Info("Reloaded TLS certificates", map[string]interface{}{
+				"files": c.files(),
+			})
+		}
+	}
+}
+
+func (c *reloadingCredentials) Close() {
+	close(c.stop)
+}
+
+func (c *reloadingCredentials) current() credentials.TransportCredentials {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return credentials.NewTLS(c.tlsCfg.Clone())
+}
+
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.current().ClientHandshake(ctx, authority, conn)
+}
+
+func (c *reloadingCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.current().ServerHandshake(conn)
+}
+
+func (c *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return c.current().Info()
+}
+
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (c *reloadingCredentials) OverrideServerName(serverName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.ServerName = serverName
+	c.tlsCfg.ServerName = serverName
+	return nil
+}
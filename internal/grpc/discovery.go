@@ -0,0 +1,396 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/balancer/leastrequest"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+)
+
+// Discovery schemes understood by NewClient in addition to the ones built
+// into grpc-go (e.g. "dns:///" or a plain "host:port"):
+//
+//	static:///10.0.0.1:50052,10.0.0.2:50052   fixed list of endpoints
+//	srv:///_grpc._tcp.product-svc.default.svc  DNS SRV records
+//	headless:///product-svc.default.svc:50052  every A/AAAA record of a headless service
+//
+// Endpoints found through these schemes are subject to outlier ejection.
+const (
+	SchemeStatic   = "static"
+	SchemeSRV      = "srv"
+	SchemeHeadless = "headless"
+)
+
+var lbPolicies = map[string]string{
+	"round_robin":   "round_robin",
+	"least_request": "least_request_experimental",
+	"pick_first":    "pick_first",
+}
+
+// discoveryOptions returns the dial options and interceptor needed to balance
+// calls across every endpoint of a backend pool.
+func discoveryOptions(target string, lbCfg config.LoadBalancingConfig) ([]grpc.DialOption, grpc.UnaryClientInterceptor, error) {
+	policy, ok := lbPolicies[lbCfg.Policy]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown load balancing policy %q", lbCfg.Policy)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, policy)),
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return opts, nil, nil
+	}
+
+	switch u.Scheme {
+	case SchemeStatic, SchemeSRV, SchemeHeadless:
+	default:
+		return opts, nil, nil
+	}
+
+	detector := newOutlierDetector(lbCfg)
+	opts = append(opts, grpc.WithResolvers(&discoveryBuilder{
+		scheme:   u.Scheme,
+		refresh:  lbCfg.RefreshInterval,
+		detector: detector,
+	}))
+
+	return opts, detector.interceptor(), nil
+}
+
+type discoveryBuilder struct {
+	scheme   string
+	refresh  time.Duration
+	detector *outlierDetector
+}
+
+func (b *discoveryBuilder) Scheme() string {
+	return b.scheme
+}
+
+func (b *discoveryBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	endpoint := target.Endpoint()
+
+	var lookup func(ctx context.Context) ([]string, error)
+	switch b.scheme {
+	case SchemeStatic:
+		lookup = func(ctx context.Context) ([]string, error) {
+			return lookupStatic(ctx, endpoint)
+		}
+	case SchemeSRV:
+		lookup = func(ctx context.Context) ([]string, error) {
+			return lookupSRV(ctx, endpoint)
+		}
+	case SchemeHeadless:
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		lookup = func(ctx context.Context) ([]string, error) {
+			return lookupHost(ctx, host, port)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discoveryResolver{
+		cc:         cc,
+		target:     endpoint,
+		lookup:     lookup,
+		refresh:    b.refresh,
+		detector:   b.detector,
+		resolveNow: make(chan struct{}, 1),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	b.detector.onChange(r.push)
+
+	go r.watch()
+
+	return r, nil
+}
+
+// lookupStatic resolves the host names in a comma separated endpoint list to
+// IPs, for the same reason as lookupSRV. IP endpoints are kept as they are.
+func lookupStatic(ctx context.Context, endpoints string) ([]string, error) {
+	var addrs []string
+	for _, endpoint := range strings.Split(endpoints, ",") {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(endpoint))
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+			continue
+		}
+		ips, err := lookupHost(ctx, host, port)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, ips...)
+	}
+	return addrs, nil
+}
+
+func lookupSRV(ctx context.Context, name string) ([]string, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the record targets to IPs so endpoints can be matched against
+	// the peer address of each call for outlier ejection.
+	var addrs []string
+	for _, record := range records {
+		ips, err := lookupHost(ctx, strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, ips...)
+	}
+	return addrs, nil
+}
+
+func lookupHost(ctx context.Context, host, port string) ([]string, error) {
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip, port))
+	}
+	return addrs, nil
+}
+
+// discoveryResolver periodically looks up the endpoints of a backend pool and
+// hands the healthy ones to the balancer.
+type discoveryResolver struct {
+	cc       resolver.ClientConn
+	target   string
+	lookup   func(ctx context.Context) ([]string, error)
+	refresh  time.Duration
+	detector *outlierDetector
+
+	mu    sync.Mutex
+	addrs []string
+
+	resolveNow chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+func (r *discoveryResolver) watch() {
+	for {
+		r.resolve()
+
+		var tick <-chan time.Time
+		if r.refresh > 0 {
+			tick = time.After(r.refresh)
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.resolveNow:
+		case <-tick:
+		}
+	}
+}
+
+func (r *discoveryResolver) resolve() {
+	ctx, cancel := context.WithTimeout(r.ctx, 10*time.Second)
+	defer cancel()
+
+	addrs, err := r.lookup(ctx)
+	if err != nil || len(addrs) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no endpoints found for %s", r.target)
+		}
+		utils.Error("Failed to resolve backend endpoints", map[string]interface{}{
+			"target": r.target,
+			"error":  err,
+		})
+		r.cc.ReportError(err)
+		return
+	}
+	sort.Strings(addrs)
+
+	r.mu.Lock()
+	r.addrs = addrs
+	r.mu.Unlock()
+
+	r.detector.track(addrs)
+	r.push()
+}
+
+// push sends the current endpoints, minus any ejected ones, to the balancer.
+func (r *discoveryResolver) push() {
+	r.mu.Lock()
+	addrs := r.detector.filter(r.addrs)
+	r.mu.Unlock()
+
+	if len(addrs) == 0 {
+		return
+	}
+
+	state := resolver.State{}
+	for _, addr := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+	}
+	if err := r.cc.UpdateState(state); err != nil {
+		utils.Warn("Balancer rejected backend endpoints", map[string]interface{}{
+			"target": r.target,
+			"error":  err,
+		})
+	}
+}
+
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *discoveryResolver) Close() {
+	r.cancel()
+}
+
+// outlierDetector ejects endpoints that fail too many calls in a row and
+// brings them back after an ejection period that grows each time the same
+// endpoint is ejected again.
+type outlierDetector struct {
+	cfg config.LoadBalancingConfig
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+	notify    func()
+}
+
+type endpointStats struct {
+	failures     int
+	ejections    int
+	ejectedUntil time.Time
+}
+
+func newOutlierDetector(cfg config.LoadBalancingConfig) *outlierDetector {
+	return &outlierDetector{
+		cfg:       cfg,
+		endpoints: make(map[string]*endpointStats),
+	}
+}
+
+func (d *outlierDetector) onChange(notify func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notify = notify
+}
+
+// track starts tracking newly discovered endpoints and forgets removed ones.
+func (d *outlierDetector) track(addrs []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		current[addr] = true
+		if _, ok := d.endpoints[addr]; !ok {
+			d.endpoints[addr] = &endpointStats{}
+		}
+	}
+	for addr := range d.endpoints {
+		if !current[addr] {
+			delete(d.endpoints, addr)
+		}
+	}
+}
+
+func (d *outlierDetector) filter(addrs []string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var healthy []string
+	for _, addr := range addrs {
+		if stats, ok := d.endpoints[addr]; ok && now.Before(stats.ejectedUntil) {
+			continue
+		}
+		healthy = append(healthy, addr)
+	}
+	return healthy
+}
+
+func (d *outlierDetector) record(addr string, failed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats, ok := d.endpoints[addr]
+	if !ok {
+		return
+	}
+
+	if !failed {
+		stats.failures = 0
+		return
+	}
+
+	stats.failures++
+	if d.cfg.OutlierConsecutiveFailures <= 0 || stats.failures < d.cfg.OutlierConsecutiveFailures {
+		return
+	}
+
+	now := time.Now()
+	ejected := 0
+	for _, s := range d.endpoints {
+		if now.Before(s.ejectedUntil) {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > len(d.endpoints)*d.cfg.OutlierMaxEjectionPercent {
+		return
+	}
+
+	stats.failures = 0
+	stats.ejections++
+	duration := d.cfg.OutlierBaseEjectionTime * time.Duration(stats.ejections)
+	if max := 10 * d.cfg.OutlierBaseEjectionTime; duration > max {
+		duration = max
+	}
+	stats.ejectedUntil = now.Add(duration)
+
+	utils.Warn("Ejecting unhealthy backend endpoint", map[string]interface{}{
+		"endpoint": addr,
+		"duration": duration.String(),
+	})
+
+	if d.notify != nil {
+		go d.notify()
+		time.AfterFunc(duration, d.notify)
+	}
+}
+
+func (d *outlierDetector) interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var p peer.Peer
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+		if p.Addr != nil {
+			d.record(p.Addr.String(), isBackendFailure(err))
+		}
+		return err
+	}
+}
@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+func TestLookupStatic(t *testing.T) {
+	addrs, err := lookupStatic(context.Background(), "localhost:50052, 10.0.0.2:50052,[::1]:50053")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"127.0.0.1:50052", "10.0.0.2:50052", "[::1]:50053"} {
+		if !slices.Contains(addrs, want) {
+			t.Errorf("got %v, want %s", addrs, want)
+		}
+	}
+	if slices.Contains(addrs, "localhost:50052") {
+		t.Errorf("got %v, want host names resolved", addrs)
+	}
+
+	if _, err := lookupStatic(context.Background(), "10.0.0.1"); err == nil {
+		t.Error("got no error for an endpoint without a port")
+	}
+}
+
+func TestOutlierDetectorEjectsStaticHostnames(t *testing.T) {
+	detector := newOutlierDetector(config.LoadBalancingConfig{
+		OutlierConsecutiveFailures: 2,
+		OutlierBaseEjectionTime:    time.Minute,
+		OutlierMaxEjectionPercent:  100,
+	})
+	addrs, err := lookupStatic(context.Background(), "localhost:50052,10.0.0.2:50052")
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector.track(addrs)
+
+	// Calls report the dialed IP as their peer address.
+	peer := (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50052}).String()
+	detector.record(peer, true)
+	detector.record(peer, true)
+
+	if healthy := detector.filter(addrs); slices.Contains(healthy, peer) || !slices.Contains(healthy, "10.0.0.2:50052") {
+		t.Errorf("got %v, want only %s ejected", healthy, peer)
+	}
+}
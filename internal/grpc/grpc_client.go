@@ -1,7 +1,9 @@
 package grpc
 
 import (
+	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -12,16 +14,52 @@ type GrpcClient interface {
 }
 
 type grpcClient struct {
-	conn *grpc.ClientConn
+	conn  *grpc.ClientConn
+	creds *reloadingCredentials
 }
 
-func NewClient(url string) (GrpcClient, error) {
-	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewClient(url string, tlsCfg config.TLSConfig, lbCfg config.LoadBalancingConfig, interceptors ...grpc.UnaryClientInterceptor) (GrpcClient, error) {
+	client := &grpcClient{}
+
+	// The request ID is attached first so every retry and hedged attempt
+	// carries it.
+	interceptors = append([]grpc.UnaryClientInterceptor{requestIDInterceptor}, interceptors...)
+
+	lbOpts, lbInterceptor, err := discoveryOptions(url, lbCfg)
+	if err != nil {
+		return nil, err
+	}
+	if lbInterceptor != nil {
+		// Outlier detection must see every attempt, so it runs innermost.
+		interceptors = append(interceptors, lbInterceptor)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if tlsCfg.Enabled {
+		creds, err := newReloadingCredentials(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		client.creds = creds
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	}
+
+	opts = append(opts, lbOpts...)
+	opts = append(opts, grpc.WithChainUnaryInterceptor(interceptors...))
+	// Every attempt, including retries and hedges, gets its own client span
+	// and carries the trace context to the backend.
+	opts = append(opts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+
+	conn, err := grpc.NewClient(url, opts...)
 	if err != nil {
+		if client.creds != nil {
+			client.creds.Close()
+		}
 		return nil, err
 	}
+	client.conn = conn
 
-	return &grpcClient{conn: conn}, nil
+	return client, nil
 }
 
 func (c *grpcClient) Conn() *grpc.ClientConn {
@@ -29,6 +67,10 @@ func (c *grpcClient) Conn() *grpc.ClientConn {
 }
 
 func (c *grpcClient) Close() {
+	if c.creds != nil {
+		c.creds.Close()
+	}
+
 	if err := c.conn.Close(); err != nil {
 		utils.Logger.Error("Failed to close gRPC connection", map[string]interface{}{
 			"error": err,
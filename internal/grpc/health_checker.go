@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// Dependency is a backend service the gateway needs in order to serve traffic.
+// A dependency that is not critical only degrades readiness when it is down.
+type Dependency struct {
+	Name     string
+	Client   GrpcClient
+	Critical bool
+}
+
+// DependencyStatus is the result of probing a single dependency.
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	ConnState string `json:"conn_state"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type HealthChecker interface {
+	Check(ctx context.Context) []DependencyStatus
+}
+
+type healthChecker struct {
+	dependencies []Dependency
+	timeout      time.Duration
+}
+
+func NewHealthChecker(timeout time.Duration, dependencies ...Dependency) HealthChecker {
+	return &healthChecker{
+		dependencies: dependencies,
+		timeout:      timeout,
+	}
+}
+
+// Check probes every dependency concurrently using the standard gRPC health
+// checking protocol. Backends that do not implement the protocol are judged
+// by the state of their connection instead.
+func (h *healthChecker) Check(ctx context.Context) []DependencyStatus {
+	results := make([]DependencyStatus, len(h.dependencies))
+
+	var wg sync.WaitGroup
+	for i, dep := range h.dependencies {
+		wg.Add(1)
+		go func(i int, dep Dependency) {
+			defer wg.Done()
+			results[i] = h.probe(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (h *healthChecker) probe(ctx context.Context, dep Dependency) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	conn := dep.Client.Conn()
+	result := DependencyStatus{
+		Name:     dep.Name,
+		Status:   HealthStatusDown,
+		Critical: dep.Critical,
+	}
+
+	// Idle connections are only dialed on first use, so kick them off here
+	// rather than reporting a backend that was never tried as down.
+	if conn.GetState() == connectivity.Idle {
+		conn.Connect()
+	}
+
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.ConnState = conn.GetState().String()
+
+	switch {
+	case err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING:
+		result.Status = HealthStatusUp
+	case err == nil:
+		result.Error = resp.Status.String()
+	case status.Code(err) == codes.Unimplemented && conn.GetState() == connectivity.Ready:
+		result.Status = HealthStatusUp
+	default:
+		result.Error = status.Convert(err).Message()
+	}
+
+	return result
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PharmaKart/gateway-svc/internal/auth"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// LocalAuthClient is an AuthClient that verifies signed JWTs itself and only
+// asks the auth service about opaque tokens.
+type LocalAuthClient struct {
+	AuthClient
+
+	verifier *auth.JWTVerifier
+}
+
+func NewLocalAuthClient(client AuthClient, verifier *auth.JWTVerifier) *LocalAuthClient {
+	return &LocalAuthClient{
+		AuthClient: client,
+		verifier:   verifier,
+	}
+}
+
+func (c *LocalAuthClient) VerifyToken(ctx context.Context, req *proto.VerifyTokenRequest) (*proto.VerifyTokenResponse, error) {
+	claims, err := c.verifier.Verify(ctx, req.GetToken())
+	if errors.Is(err, auth.ErrNotJWT) {
+		return c.AuthClient.VerifyToken(ctx, req)
+	}
+	if err != nil {
+		utils.WarnContext(ctx, "Rejected JWT", map[string]interface{}{
+			"error": err,
+		})
+		return &proto.VerifyTokenResponse{
+			Success: false,
+			Message: "Invalid or expired token",
+			Error: &proto.Error{
+				Type:    utils.ErrAuth.Code,
+				Message: "Invalid or expired token",
+			},
+		}, nil
+	}
+
+	resp := &proto.VerifyTokenResponse{
+		Success:   true,
+		Message:   "Token verified",
+		UserId:    claims.UserID,
+		Role:      claims.Role,
+		TokenId:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Mfa:       claims.MFA,
+	}
+	if !claims.IssuedAt.IsZero() {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return resp, nil
+}
@@ -0,0 +1,25 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// NewMetricsInterceptor records the outcome and latency of every call made
+// to the named backend service.
+func NewMetricsInterceptor(service string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		code := status.Code(err).String()
+		utils.GRPCClientRequestsTotal.WithLabelValues(service, method, code).Inc()
+		utils.GRPCClientRequestDuration.WithLabelValues(service, method, code).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
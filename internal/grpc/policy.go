@@ -0,0 +1,262 @@
+package grpc
+
+import (
+	"context"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// callPolicy is the resolved policy for a single backend method.
+type callPolicy struct {
+	timeout    time.Duration
+	idempotent bool
+}
+
+// NewPolicyInterceptor returns a unary client interceptor that applies the
+// configured deadline to every call to the named service, and retries or
+// hedges idempotent reads. Retries and hedges share a per-service budget so
+// they cannot amplify an outage.
+func NewPolicyInterceptor(service string, cfg config.RPCPolicyConfig) grpc.UnaryClientInterceptor {
+	idempotent := make(map[string]bool, len(cfg.IdempotentMethods))
+	for _, method := range cfg.IdempotentMethods {
+		idempotent[method] = true
+	}
+
+	var (
+		mu       sync.Mutex
+		policies = make(map[string]callPolicy)
+	)
+	resolve := func(method string) callPolicy {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if p, ok := policies[method]; ok {
+			return p
+		}
+
+		name := path.Base(method)
+		p := callPolicy{
+			timeout:    cfg.Timeout,
+			idempotent: idempotent[method] || idempotent[name],
+		}
+		if d, ok := cfg.ServiceTimeouts[service]; ok {
+			p.timeout = d
+		}
+		if d, ok := cfg.MethodTimeouts[name]; ok {
+			p.timeout = d
+		}
+		if d, ok := cfg.MethodTimeouts[method]; ok {
+			p.timeout = d
+		}
+
+		policies[method] = p
+		return p
+	}
+
+	budget := newRetryBudget(cfg.RetryBudgetRatio, cfg.RetryBudgetMin)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		p := resolve(method)
+
+		if p.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+		}
+
+		budget.deposit()
+
+		if !p.idempotent || cfg.RetryMaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		if cfg.HedgeDelay > 0 {
+			return hedge(ctx, service, method, req, reply, cc, invoker, cfg, budget, opts...)
+		}
+
+		return retry(ctx, service, method, req, reply, cc, invoker, cfg, budget, opts...)
+	}
+}
+
+func retry(ctx context.Context, service, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, cfg config.RPCPolicyConfig, budget *retryBudget, opts ...grpc.CallOption) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		if !isRetryable(err) || attempt >= cfg.RetryMaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		if !budget.withdraw() {
+			utils.WarnContext(ctx, "Retry budget exhausted", map[string]interface{}{
+				"service": service,
+				"method":  method,
+			})
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff(cfg, attempt)):
+		}
+
+		utils.WarnContext(ctx, "Retrying backend call", map[string]interface{}{
+			"service": service,
+			"method":  method,
+			"attempt": attempt + 1,
+			"error":   err,
+		})
+	}
+}
+
+// hedge sends the request and, if no response has arrived after HedgeDelay,
+// sends another copy, up to RetryMaxAttempts in flight. The first successful
+// or non-retryable response wins and the remaining attempts are cancelled.
+func hedge(ctx context.Context, service, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, cfg config.RPCPolicyConfig, budget *retryBudget, opts ...grpc.CallOption) error {
+	msg, ok := reply.(protobuf.Message)
+	if !ok {
+		return retry(ctx, service, method, req, reply, cc, invoker, cfg, budget, opts...)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		reply protobuf.Message
+		err   error
+	}
+	results := make(chan result, cfg.RetryMaxAttempts)
+
+	send := func() {
+		r := msg.ProtoReflect().New().Interface()
+		err := invoker(ctx, method, req, r, cc, opts...)
+		results <- result{reply: r, err: err}
+	}
+
+	go send()
+	inFlight, sent := 1, 1
+
+	timer := time.NewTimer(cfg.HedgeDelay)
+	defer timer.Stop()
+
+	var lastErr error
+	for inFlight > 0 {
+		select {
+		case res := <-results:
+			inFlight--
+			if !isRetryable(res.err) {
+				if res.err == nil {
+					protobuf.Merge(msg, res.reply)
+				}
+				return res.err
+			}
+			lastErr = res.err
+			if inFlight == 0 && sent < cfg.RetryMaxAttempts && ctx.Err() == nil && budget.withdraw() {
+				go send()
+				inFlight++
+				sent++
+			}
+		case <-timer.C:
+			if sent < cfg.RetryMaxAttempts && budget.withdraw() {
+				utils.WarnContext(ctx, "Hedging backend call", map[string]interface{}{
+					"service": service,
+					"method":  method,
+					"attempt": sent + 1,
+				})
+				go send()
+				inFlight++
+				sent++
+				timer.Reset(cfg.HedgeDelay)
+			}
+		}
+	}
+
+	return lastErr
+}
+
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before the given retry attempt using capped
+// exponential backoff with full jitter.
+func backoff(cfg config.RPCPolicyConfig, attempt int) time.Duration {
+	d := cfg.RetryInitialBackoff << (attempt - 1)
+	if d <= 0 || d > cfg.RetryMaxBackoff {
+		d = cfg.RetryMaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// retryBudget is a token bucket that earns a fraction of a token for every
+// call and spends a whole token for every retry or hedge, with a small floor
+// of retries per second so low-traffic services can still retry.
+type retryBudget struct {
+	mu         sync.Mutex
+	ratio      float64
+	minPerSec  float64
+	tokens     float64
+	max        float64
+	lastRefill time.Time
+}
+
+func newRetryBudget(ratio float64, minPerSecond int) *retryBudget {
+	max := float64(minPerSecond)
+	if max < 1 {
+		max = 1
+	}
+	return &retryBudget{
+		ratio:      ratio,
+		minPerSec:  float64(minPerSecond),
+		tokens:     max,
+		max:        max * 10,
+		lastRefill: time.Now(),
+	}
+}
+
+func (b *retryBudget) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.lastRefill).Seconds() * b.minPerSec
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+	b.lastRefill = now
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,25 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDMetadataKey is the outgoing metadata key carrying the request ID.
+var requestIDMetadataKey = strings.ToLower(utils.RequestIDHeader)
+
+// requestIDInterceptor forwards the request ID found in the call context to
+// the backend as outgoing metadata, so backend logs can be correlated with
+// gateway logs.
+func requestIDInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if requestID := utils.RequestIDFromContext(ctx); requestID != "" {
+		if md, ok := metadata.FromOutgoingContext(ctx); !ok || len(md.Get(requestIDMetadataKey)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, requestID)
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
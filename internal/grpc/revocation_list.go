@@ -0,0 +1,218 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// RevocationList is the gateway's copy of the tokens revoked by the auth
+// service, kept in sync by polling ListRevokedTokens. Logouts through this
+// gateway are added immediately; other instances see them on their next sync.
+type RevocationList struct {
+	client       AuthClient
+	cfg          config.RevocationConfig
+	invalidators []TokenInvalidator
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // token ID to token expiry
+	users    map[string]userRevocation
+	lastSeen int64
+
+	done chan struct{}
+	once sync.Once
+}
+
+// userRevocation revokes every token of a user issued before revokedAt. Token
+// issue times are whole seconds, so revokedAt is kept in whole seconds too and
+// a token issued in the second of the revocation is accepted.
+type userRevocation struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+// NewRevocationList creates an empty revocation list. The invalidators are
+// told about every revocation so cached verification results are dropped.
+func NewRevocationList(client AuthClient, cfg config.RevocationConfig, invalidators ...TokenInvalidator) (*RevocationList, error) {
+	if cfg.SyncInterval <= 0 {
+		return nil, errors.New("TOKEN_REVOCATION_SYNC_INTERVAL must be positive")
+	}
+	if cfg.Retention <= 0 {
+		return nil, errors.New("TOKEN_REVOCATION_RETENTION must be positive")
+	}
+
+	return &RevocationList{
+		client:       client,
+		cfg:          cfg,
+		invalidators: invalidators,
+		tokens:       make(map[string]time.Time),
+		users:        make(map[string]userRevocation),
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Start loads the current revocations in the background and keeps polling
+// for new ones.
+func (l *RevocationList) Start() {
+	go func() {
+		l.sync()
+
+		ticker := time.NewTicker(l.cfg.SyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.done:
+				return
+			case <-ticker.C:
+				l.sync()
+			}
+		}
+	}()
+}
+
+// Stop stops polling.
+func (l *RevocationList) Stop() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// IsRevoked reports whether the token was revoked, either by ID or because
+// all of the user's tokens issued before some point were. A token without an
+// issue time is treated as revoked once its user has been.
+func (l *RevocationList) IsRevoked(tokenID, userID string, issuedAt time.Time) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if tokenID != "" {
+		if _, ok := l.tokens[tokenID]; ok {
+			return true
+		}
+	}
+	if revocation, ok := l.users[userID]; ok {
+		return issuedAt.IsZero() || issuedAt.Unix() < revocation.revokedAt.Unix()
+	}
+	return false
+}
+
+// RevokeToken revokes a single token until it expires.
+func (l *RevocationList) RevokeToken(tokenID, userID string, expiresAt time.Time) {
+	if tokenID == "" {
+		return
+	}
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(l.cfg.Retention)
+	}
+
+	l.mu.Lock()
+	l.tokens[tokenID] = expiresAt
+	l.mu.Unlock()
+
+	l.invalidate(userID)
+}
+
+// RevokeUser revokes every token of the user issued before the current
+// second.
+func (l *RevocationList) RevokeUser(userID string) {
+	now := time.Now().Truncate(time.Second)
+
+	l.mu.Lock()
+	l.revokeUser(userID, userRevocation{revokedAt: now, expiresAt: now.Add(l.cfg.Retention)})
+	l.mu.Unlock()
+
+	l.invalidate(userID)
+}
+
+// revokeUser records a user revocation unless a later one is already known,
+// so revocations synced more than once are harmless. l.mu must be held.
+func (l *RevocationList) revokeUser(userID string, revocation userRevocation) {
+	if current, ok := l.users[userID]; ok && !revocation.revokedAt.After(current.revokedAt) {
+		return
+	}
+	l.users[userID] = revocation
+}
+
+func (l *RevocationList) sync() {
+	// Revocation times are whole seconds, and more may be recorded in the
+	// second of the last one seen, so that second is fetched again.
+	l.mu.RLock()
+	since := l.lastSeen
+	l.mu.RUnlock()
+	if since > 0 {
+		since--
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.cfg.SyncInterval)
+	defer cancel()
+
+	resp, err := l.client.ListRevokedTokens(ctx, &proto.ListRevokedTokensRequest{Since: since})
+	if err != nil {
+		utils.Error("Failed to sync revoked tokens", map[string]interface{}{
+			"error": err,
+		})
+		return
+	}
+	if !resp.GetSuccess() {
+		utils.Error("Failed to sync revoked tokens", map[string]interface{}{
+			"error": resp.GetMessage(),
+		})
+		return
+	}
+
+	now := time.Now()
+	var userIDs []string
+
+	l.mu.Lock()
+	for _, revoked := range resp.GetRevokedTokens() {
+		expiresAt := time.Unix(revoked.GetExpiresAt(), 0)
+		if revoked.GetExpiresAt() == 0 {
+			expiresAt = now.Add(l.cfg.Retention)
+		}
+
+		if revoked.GetTokenId() != "" {
+			l.tokens[revoked.GetTokenId()] = expiresAt
+		} else {
+			l.revokeUser(revoked.GetUserId(), userRevocation{
+				revokedAt: time.Unix(revoked.GetRevokedAt(), 0),
+				expiresAt: expiresAt,
+			})
+		}
+		if revoked.GetRevokedAt() > l.lastSeen {
+			l.lastSeen = revoked.GetRevokedAt()
+		}
+		userIDs = append(userIDs, revoked.GetUserId())
+	}
+	l.prune(now)
+	l.mu.Unlock()
+
+	for _, userID := range userIDs {
+		l.invalidate(userID)
+	}
+}
+
+// prune forgets revocations of tokens that have expired anyway.
+func (l *RevocationList) prune(now time.Time) {
+	for tokenID, expiresAt := range l.tokens {
+		if now.After(expiresAt) {
+			delete(l.tokens, tokenID)
+		}
+	}
+	for userID, revocation := range l.users {
+		if now.After(revocation.expiresAt) {
+			delete(l.users, userID)
+		}
+	}
+}
+
+func (l *RevocationList) invalidate(userID string) {
+	if userID == "" {
+		return
+	}
+	for _, invalidator := range l.invalidators {
+		invalidator.InvalidateUser(userID)
+	}
+}
@@ -0,0 +1,174 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+func init() {
+	utils.InitLogger(config.LoggingConfig{Level: "fatal"})
+}
+
+// fakeRevocationSource answers ListRevokedTokens like the auth service, with
+// the revocations recorded after Since.
+type fakeRevocationSource struct {
+	AuthClient
+
+	revoked []*proto.RevokedToken
+	since   []int64
+}
+
+func (f *fakeRevocationSource) ListRevokedTokens(ctx context.Context, req *proto.ListRevokedTokensRequest) (*proto.ListRevokedTokensResponse, error) {
+	f.since = append(f.since, req.Since)
+
+	var revoked []*proto.RevokedToken
+	for _, token := range f.revoked {
+		if token.RevokedAt > req.Since {
+			revoked = append(revoked, token)
+		}
+	}
+	return &proto.ListRevokedTokensResponse{Success: true, RevokedTokens: revoked}, nil
+}
+
+// fakeInvalidator records the users whose cached tokens were dropped.
+type fakeInvalidator struct {
+	users []string
+}
+
+func (f *fakeInvalidator) InvalidateToken(token string) {}
+
+func (f *fakeInvalidator) InvalidateUser(userID string) {
+	f.users = append(f.users, userID)
+}
+
+var testRevocationConfig = config.RevocationConfig{SyncInterval: time.Minute, Retention: time.Hour}
+
+func newTestRevocationList(t *testing.T, source *fakeRevocationSource, invalidators ...TokenInvalidator) *RevocationList {
+	t.Helper()
+
+	l, err := NewRevocationList(source, testRevocationConfig, invalidators...)
+	if err != nil {
+		t.Fatalf("failed to create revocation list: %v", err)
+	}
+	return l
+}
+
+func TestNewRevocationListValidatesConfig(t *testing.T) {
+	for _, cfg := range []config.RevocationConfig{
+		{SyncInterval: 0, Retention: time.Hour},
+		{SyncInterval: -time.Second, Retention: time.Hour},
+		{SyncInterval: time.Minute, Retention: 0},
+	} {
+		if _, err := NewRevocationList(&fakeRevocationSource{}, cfg); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+}
+
+func TestRevocationListIsRevoked(t *testing.T) {
+	invalidator := &fakeInvalidator{}
+	l := newTestRevocationList(t, &fakeRevocationSource{}, invalidator)
+
+	l.RevokeToken("t1", "u1", time.Now().Add(time.Hour))
+	if !l.IsRevoked("t1", "u2", time.Now()) {
+		t.Error("revoked token is accepted")
+	}
+	if l.IsRevoked("t2", "u1", time.Now()) {
+		t.Error("other token of the user is rejected")
+	}
+
+	l.RevokeUser("u3")
+	now := time.Now()
+	if !l.IsRevoked("", "u3", now.Add(-time.Second)) {
+		t.Error("token issued before the user was revoked is accepted")
+	}
+	if !l.IsRevoked("", "u3", time.Time{}) {
+		t.Error("token without an issue time is accepted")
+	}
+	// A new login in the same second gets a token with the same iat.
+	if l.IsRevoked("", "u3", now.Truncate(time.Second)) {
+		t.Error("token issued in the second of the revocation is rejected")
+	}
+	if l.IsRevoked("", "u3", now.Add(time.Second)) {
+		t.Error("token issued after the revocation is rejected")
+	}
+
+	if len(invalidator.users) != 2 || invalidator.users[0] != "u1" || invalidator.users[1] != "u3" {
+		t.Errorf("invalidated users %v, want [u1 u3]", invalidator.users)
+	}
+}
+
+func TestRevocationListPrune(t *testing.T) {
+	l := newTestRevocationList(t, &fakeRevocationSource{})
+	now := time.Now()
+
+	l.RevokeToken("expired", "u1", now.Add(-time.Minute))
+	l.RevokeToken("valid", "u1", now.Add(time.Minute))
+	l.users["gone"] = userRevocation{revokedAt: now.Add(-2 * time.Hour), expiresAt: now.Add(-time.Hour)}
+	l.users["kept"] = userRevocation{revokedAt: now, expiresAt: now.Add(time.Hour)}
+
+	l.prune(now)
+
+	if _, ok := l.tokens["expired"]; ok {
+		t.Error("expired token revocation was kept")
+	}
+	if _, ok := l.tokens["valid"]; !ok {
+		t.Error("token revocation was pruned before the token expired")
+	}
+	if _, ok := l.users["gone"]; ok {
+		t.Error("expired user revocation was kept")
+	}
+	if _, ok := l.users["kept"]; !ok {
+		t.Error("user revocation was pruned before it expired")
+	}
+}
+
+func TestRevocationListSync(t *testing.T) {
+	now := time.Now().Unix()
+	source := &fakeRevocationSource{revoked: []*proto.RevokedToken{
+		{TokenId: "t1", UserId: "u1", RevokedAt: now - 10, ExpiresAt: now + 3600},
+		{UserId: "u2", RevokedAt: now},
+	}}
+	invalidator := &fakeInvalidator{}
+	l := newTestRevocationList(t, source, invalidator)
+
+	l.sync()
+	if !l.IsRevoked("t1", "u1", time.Now()) {
+		t.Error("synced token revocation is missing")
+	}
+	if !l.IsRevoked("", "u2", time.Unix(now-1, 0)) {
+		t.Error("synced user revocation is missing")
+	}
+
+	// Another instance records a revocation in the same second as the last
+	// one seen.
+	source.revoked = append(source.revoked, &proto.RevokedToken{TokenId: "t2", UserId: "u3", RevokedAt: now, ExpiresAt: now + 3600})
+	l.sync()
+	if !l.IsRevoked("t2", "u3", time.Now()) {
+		t.Error("revocation in the second of the last sync was missed")
+	}
+
+	if len(source.since) != 2 || source.since[0] != 0 || source.since[1] != now-1 {
+		t.Errorf("synced since %v, want [0 %d]", source.since, now-1)
+	}
+}
+
+func TestRevocationListSyncKeepsLaterUserRevocation(t *testing.T) {
+	now := time.Now()
+	source := &fakeRevocationSource{revoked: []*proto.RevokedToken{
+		{UserId: "u1", RevokedAt: now.Add(-time.Minute).Unix()},
+	}}
+	l := newTestRevocationList(t, source)
+
+	l.RevokeUser("u1")
+	l.sync()
+
+	if !l.IsRevoked("", "u1", now.Add(-time.Second)) {
+		t.Error("synced earlier revocation replaced the later local one")
+	}
+}
@@ -0,0 +1,220 @@
+package grpc
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"golang.org/x/sync/singleflight"
+)
+
+// TokenInvalidator drops cached verification results, e.g. on logout or
+// when a user's tokens are revoked.
+type TokenInvalidator interface {
+	InvalidateToken(token string)
+	InvalidateUser(userID string)
+}
+
+// CachingAuthClient is an AuthClient that reuses VerifyToken results.
+// Entries are keyed by the SHA-256 of the token so raw tokens are never kept
+// in memory, and concurrent verifications of the same token share one call.
+// Rejected tokens are kept in their own LRU list, so they only ever evict
+// each other. Backend errors are never cached.
+type CachingAuthClient struct {
+	AuthClient
+
+	cfg   config.TokenCacheConfig
+	group singleflight.Group
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	negative *list.List
+}
+
+type tokenCacheEntry struct {
+	key       string
+	resp      *proto.VerifyTokenResponse
+	expiresAt time.Time
+}
+
+// list returns the LRU list holding entries like this one.
+func (e *tokenCacheEntry) list(c *CachingAuthClient) *list.List {
+	if e.resp.GetSuccess() {
+		return c.lru
+	}
+	return c.negative
+}
+
+func NewCachingAuthClient(client AuthClient, cfg config.TokenCacheConfig) *CachingAuthClient {
+	return &CachingAuthClient{
+		AuthClient: client,
+		cfg:        cfg,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		negative:   list.New(),
+	}
+}
+
+func (c *CachingAuthClient) VerifyToken(ctx context.Context, req *proto.VerifyTokenRequest) (*proto.VerifyTokenResponse, error) {
+	if !c.cfg.Enabled {
+		return c.AuthClient.VerifyToken(ctx, req)
+	}
+
+	key := hashToken(req.GetToken())
+	if resp, ok := c.get(key); ok {
+		if resp.GetSuccess() {
+			utils.TokenCacheRequestsTotal.WithLabelValues("hit").Inc()
+		} else {
+			utils.TokenCacheRequestsTotal.WithLabelValues("negative_hit").Inc()
+		}
+		return resp, nil
+	}
+	utils.TokenCacheRequestsTotal.WithLabelValues("miss").Inc()
+
+	// The shared call must not be cancelled by whichever caller started it.
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		resp, err := c.AuthClient.VerifyToken(context.WithoutCancel(ctx), req)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, resp, c.ttl(req.GetToken(), resp))
+		return resp, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*proto.VerifyTokenResponse), nil
+	}
+}
+
+// InvalidateToken drops the cached result for token.
+func (c *CachingAuthClient) InvalidateToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[hashToken(token)]; ok {
+		c.remove(element)
+	}
+}
+
+// InvalidateUser drops every cached result for the user's tokens.
+func (c *CachingAuthClient) InvalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*tokenCacheEntry).resp.GetUserId() == userID {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+func (c *CachingAuthClient) get(key string) (*proto.VerifyTokenResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*tokenCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+	entry.list(c).MoveToFront(element)
+	return entry.resp, true
+}
+
+func (c *CachingAuthClient) put(key string, resp *proto.VerifyTokenResponse, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &tokenCacheEntry{
+		key:       key,
+		resp:      resp,
+		expiresAt: time.Now().Add(ttl),
+	}
+	entries, limit := c.lru, c.cfg.MaxEntries
+	if !resp.GetSuccess() {
+		entries, limit = c.negative, c.cfg.MaxNegativeEntries
+	}
+	c.entries[key] = entries.PushFront(entry)
+
+	for limit > 0 && entries.Len() > limit {
+		c.remove(entries.Back())
+	}
+}
+
+func (c *CachingAuthClient) remove(element *list.Element) {
+	entry := element.Value.(*tokenCacheEntry)
+	entry.list(c).Remove(element)
+	delete(c.entries, entry.key)
+}
+
+// ttl returns how long a verification result may be reused: NegativeTTL for
+// rejected tokens, otherwise TTL capped at the token's own expiry.
+func (c *CachingAuthClient) ttl(token string, resp *proto.VerifyTokenResponse) time.Duration {
+	if !resp.GetSuccess() {
+		return c.cfg.NegativeTTL
+	}
+
+	ttl := c.cfg.TTL
+	if expiry, ok := tokenExpiry(token); ok {
+		if untilExpiry := time.Until(expiry); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+	return ttl
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenExpiry reads the exp claim of a JWT. The signature is not checked:
+// the token has already been verified by the auth service, this only bounds
+// how long that verification is reused.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(*claims.Exp), 0), true
+}
@@ -0,0 +1,207 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+// fakeVerifier accepts tokens of the form "<user>.<anything>" and rejects
+// those starting with "bad". When release is set, calls wait for it.
+type fakeVerifier struct {
+	AuthClient
+
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeVerifier) VerifyToken(ctx context.Context, req *proto.VerifyTokenRequest) (*proto.VerifyTokenResponse, error) {
+	if f.calls.Add(1) == 1 && f.started != nil {
+		close(f.started)
+	}
+	if f.release != nil {
+		<-f.release
+	}
+	if strings.HasPrefix(req.GetToken(), "bad") {
+		return &proto.VerifyTokenResponse{Success: false, Message: "invalid token"}, nil
+	}
+	userID, _, _ := strings.Cut(req.GetToken(), ".")
+	return &proto.VerifyTokenResponse{Success: true, UserId: userID}, nil
+}
+
+var testTokenCacheConfig = config.TokenCacheConfig{
+	Enabled:            true,
+	TTL:                time.Hour,
+	NegativeTTL:        time.Minute,
+	MaxEntries:         100,
+	MaxNegativeEntries: 100,
+}
+
+// testJWT returns an unsigned JWT carrying only an exp claim.
+func testJWT(userID string, exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return userID + "." + payload + ".sig"
+}
+
+func verify(t *testing.T, c *CachingAuthClient, token string) *proto.VerifyTokenResponse {
+	t.Helper()
+
+	resp, err := c.VerifyToken(context.Background(), &proto.VerifyTokenRequest{Token: token})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp
+}
+
+func TestCachingAuthClientSharesConcurrentVerifications(t *testing.T) {
+	verifier := &fakeVerifier{started: make(chan struct{}), release: make(chan struct{})}
+	c := NewCachingAuthClient(verifier, testTokenCacheConfig)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		ctx := context.Background()
+		if i == 0 {
+			ctx = cancelled
+		}
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			resp, err := c.VerifyToken(ctx, &proto.VerifyTokenRequest{Token: "u1.token"})
+			if err == nil && resp.GetUserId() != "u1" {
+				err = fmt.Errorf("got user %q, want u1", resp.GetUserId())
+			}
+			errs <- err
+		}(ctx)
+	}
+
+	// Cancelling one caller neither fails the others nor the shared call.
+	<-verifier.started
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(verifier.release)
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			failed++
+			if err != context.Canceled {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	}
+	if failed > 1 {
+		t.Errorf("%d callers failed, want at most the cancelled one", failed)
+	}
+	if calls := verifier.calls.Load(); calls != 1 {
+		t.Errorf("got %d calls to the auth service, want 1", calls)
+	}
+	if resp := verify(t, c, "u1.token"); resp.GetUserId() != "u1" || verifier.calls.Load() != 1 {
+		t.Error("the shared result was not cached")
+	}
+}
+
+func TestCachingAuthClientTTL(t *testing.T) {
+	c := NewCachingAuthClient(&fakeVerifier{}, testTokenCacheConfig)
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+		want  time.Duration
+	}{
+		{name: "no expiry", token: "u1.opaque", want: time.Hour},
+		{name: "expires after the TTL", token: testJWT("u1", now.Add(2*time.Hour)), want: time.Hour},
+		{name: "expires before the TTL", token: testJWT("u1", now.Add(10*time.Minute)), want: 10 * time.Minute},
+		{name: "expired", token: testJWT("u1", now.Add(-time.Minute)), want: -time.Minute},
+		{name: "rejected", token: "bad.token", want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		resp, _ := c.AuthClient.VerifyToken(context.Background(), &proto.VerifyTokenRequest{Token: tt.token})
+		if got := c.ttl(tt.token, resp); got < tt.want-time.Second || got > tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// An expired token is not cached at all
+	verifier := &fakeVerifier{}
+	c = NewCachingAuthClient(verifier, testTokenCacheConfig)
+	expired := testJWT("u1", now.Add(-time.Minute))
+	verify(t, c, expired)
+	verify(t, c, expired)
+	if calls := verifier.calls.Load(); calls != 2 {
+		t.Errorf("got %d calls for an expired token, want 2", calls)
+	}
+}
+
+func TestCachingAuthClientInvalidate(t *testing.T) {
+	verifier := &fakeVerifier{}
+	c := NewCachingAuthClient(verifier, testTokenCacheConfig)
+	tokens := []string{"u1.a", "u1.b", "u2.c"}
+	for _, token := range tokens {
+		verify(t, c, token)
+	}
+
+	c.InvalidateUser("u1")
+	c.InvalidateToken("u2.c")
+	c.InvalidateToken("u3.unknown")
+	for _, token := range tokens {
+		verify(t, c, token)
+	}
+	if calls := verifier.calls.Load(); calls != 6 {
+		t.Errorf("got %d calls, want every token verified again", calls)
+	}
+
+	c.InvalidateUser("u1")
+	for _, token := range tokens {
+		verify(t, c, token)
+	}
+	if calls := verifier.calls.Load(); calls != 8 {
+		t.Errorf("got %d calls, want only the tokens of u1 dropped", calls)
+	}
+}
+
+func TestCachingAuthClientCapsRejectedTokensSeparately(t *testing.T) {
+	verifier := &fakeVerifier{}
+	cfg := testTokenCacheConfig
+	cfg.MaxEntries = 2
+	cfg.MaxNegativeEntries = 3
+	c := NewCachingAuthClient(verifier, cfg)
+
+	verify(t, c, "u1.a")
+	verify(t, c, "u2.b")
+	for i := 0; i < 50; i++ {
+		if resp := verify(t, c, fmt.Sprintf("bad.%d", i)); resp.GetSuccess() {
+			t.Fatal("bad token accepted")
+		}
+	}
+
+	verify(t, c, "u1.a")
+	verify(t, c, "u2.b")
+	if calls := verifier.calls.Load(); calls != 52 {
+		t.Errorf("got %d calls, want the valid tokens still cached", calls)
+	}
+	if c.lru.Len() != 2 || c.negative.Len() != 3 || len(c.entries) != 5 {
+		t.Errorf("got %d valid and %d rejected entries, %d in total, want 2 and 3", c.lru.Len(), c.negative.Len(), len(c.entries))
+	}
+
+	// Valid tokens still evict each other
+	verify(t, c, "u3.c")
+	verify(t, c, "u2.b")
+	if calls := verifier.calls.Load(); calls != 53 || c.lru.Len() != 2 {
+		t.Errorf("got %d calls, want the least recently used valid token evicted", calls)
+	}
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/notify"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	purposeEmailVerification = "email_verification"
+	purposePasswordReset     = "password_reset"
+)
+
+// accountRequestAccepted is returned for every verification or reset request,
+// whether or not the email belongs to an account, so the endpoints cannot be
+// used to find out which emails are registered.
+const accountRequestAccepted = "If an account exists for this email, a message has been sent to it"
+
+// AccountLimiter limits how often verification and reset emails can be
+// requested, both per email address and per client IP. The counts are kept
+// in process, so each replica enforces the limits on its own and the
+// effective limit grows with the replica count.
+type AccountLimiter struct {
+	perEmail *utils.RateLimiter
+	perIP    *utils.RateLimiter
+}
+
+func NewAccountLimiter(cfg config.AccountRateLimitConfig) *AccountLimiter {
+	return &AccountLimiter{
+		perEmail: utils.NewRateLimiter(cfg.PerEmail, cfg.Window),
+		perIP:    utils.NewRateLimiter(cfg.PerIP, cfg.Window),
+	}
+}
+
+// allow reports whether the request may proceed, responding with 429 when it
+// may not. Emails are hashed so the limiter does not hold addresses in memory.
+func (l *AccountLimiter) allow(c *gin.Context, email string) bool {
+	ok, retryAfter := l.perIP.Allow(c.ClientIP())
+	if ok {
+		sum := sha256.Sum256([]byte(email))
+		ok, retryAfter = l.perEmail.Allow(hex.EncodeToString(sum[:]))
+	}
+	if ok {
+		return true
+	}
+
+	respondRateLimited(c, retryAfter)
+	return false
+}
+
+// respondRateLimited rejects the request with 429 and a Retry-After header.
+func respondRateLimited(c *gin.Context, retryAfter time.Duration) {
+	utils.WarnContext(c.Request.Context(), "Rejecting request, rate limit exceeded", map[string]interface{}{
+		"path":        c.Request.URL.Path,
+		"retry_after": retryAfter.String(),
+	})
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	utils.RespondWithError(c, utils.ErrRateLimit, "Too many requests, please retry later", nil)
+}
+
+type AccountEmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// RequestEmailVerification sends an email verification link.
+// @Summary Request email verification
+// @Description Sends an email verification link to the address. The response is the same whether or not an account exists.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body AccountEmailRequest true "Email address"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Router /api/v1/email/verification [post]
+func RequestEmailVerification(authClient grpc.AuthClient, sender notify.Sender, limiter *AccountLimiter, cfg config.NotificationConfig) gin.HandlerFunc {
+	return requestAccountToken(authClient, sender, limiter, purposeEmailVerification, func(firstName, token string) notify.Message {
+		return notify.Message{
+			Subject: "Verify your PharmaKart email",
+			Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email by opening the link below:\n\n%s\n\nIf you did not create a PharmaKart account, you can ignore this email.\n",
+				greetingName(firstName), accountLink(cfg.LinkBaseURL, "/verify-email", token)),
+		}
+	})
+}
+
+// RequestPasswordReset sends a password reset link.
+// @Summary Request password reset
+// @Description Sends a password reset link to the address. The response is the same whether or not an account exists.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body AccountEmailRequest true "Email address"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Router /api/v1/password/reset [post]
+func RequestPasswordReset(authClient grpc.AuthClient, sender notify.Sender, limiter *AccountLimiter, cfg config.NotificationConfig) gin.HandlerFunc {
+	return requestAccountToken(authClient, sender, limiter, purposePasswordReset, func(firstName, token string) notify.Message {
+		return notify.Message{
+			Subject: "Reset your PharmaKart password",
+			Body: fmt.Sprintf("Hi %s,\n\nYou can choose a new password by opening the link below:\n\n%s\n\nIf you did not ask to reset your password, you can ignore this email.\n",
+				greetingName(firstName), accountLink(cfg.LinkBaseURL, "/reset-password", token)),
+		}
+	})
+}
+
+// requestAccountToken asks the auth service for a token and emails it. The
+// email is sent in the background so that response times do not reveal
+// whether the account exists.
+func requestAccountToken(authClient grpc.AuthClient, sender notify.Sender, limiter *AccountLimiter, purpose string, message func(firstName, token string) notify.Message) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AccountEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"email": "Email is required"})
+			return
+		}
+
+		email := strings.ToLower(strings.TrimSpace(req.Email))
+		if !limiter.allow(c, email) {
+			return
+		}
+
+		ctx := context.WithoutCancel(c.Request.Context())
+		go func() {
+			resp, err := authClient.CreateAccountToken(ctx, &proto.CreateAccountTokenRequest{
+				Email:   email,
+				Purpose: purpose,
+			})
+			if err != nil {
+				utils.ErrorContext(ctx, "Failed to create account token", map[string]interface{}{
+					"error":   err,
+					"purpose": purpose,
+				})
+				return
+			}
+			if !resp.Success || !resp.AccountExists {
+				if !resp.Success {
+					utils.WarnContext(ctx, "Failed to create account token", map[string]interface{}{
+						"error":   resp.Message,
+						"purpose": purpose,
+					})
+				}
+				return
+			}
+
+			msg := message(resp.FirstName, resp.Token)
+			msg.To = email
+			if err := sender.Send(ctx, msg); err != nil {
+				utils.ErrorContext(ctx, "Failed to send account email", map[string]interface{}{
+					"error":   err,
+					"purpose": purpose,
+				})
+				return
+			}
+
+			utils.InfoContext(ctx, "Account email sent", map[string]interface{}{
+				"purpose": purpose,
+			})
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": accountRequestAccepted,
+		})
+	}
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail confirms an email address.
+// @Summary Verify email
+// @Description Confirms the email address with the token from the verification email. Tokens are single use.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} proto.VerifyEmailResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/email/verification/confirm [post]
+func VerifyEmail(authClient grpc.AuthClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"token": "Token is required"})
+			return
+		}
+
+		resp, err := authClient.VerifyEmail(c.Request.Context(), &proto.VerifyEmailRequest{
+			Token: req.Token,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to verify email", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to verify email")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to verify email", map[string]interface{}{
+				"error": resp.Message,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrAuth, resp.Message, nil)
+			return
+		}
+
+		utils.InfoContext(c.Request.Context(), "Email verified", map[string]interface{}{
+			"user_id": resp.UserId,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": resp.Message,
+		})
+	}
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ResetPassword sets a new password.
+// @Summary Reset password
+// @Description Sets a new password with the token from the reset email. Tokens are single use, and every session of the user is revoked.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} proto.ResetPasswordResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/password/reset/confirm [post]
+func ResetPassword(authClient grpc.AuthClient, revocations *grpc.RevocationList) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": "Token and new password are required"})
+			return
+		}
+
+		resp, err := authClient.ResetPassword(c.Request.Context(), &proto.ResetPasswordRequest{
+			Token:       req.Token,
+			NewPassword: req.NewPassword,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to reset password", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to reset password")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to reset password", map[string]interface{}{
+				"error": resp.Message,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrAuth, resp.Message, nil)
+			return
+		}
+
+		// The auth service revokes the user's sessions; reject them here too
+		// without waiting for the next revocation sync.
+		revocations.RevokeUser(resp.UserId)
+
+		utils.InfoContext(c.Request.Context(), "Password reset", map[string]interface{}{
+			"user_id": resp.UserId,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": resp.Message,
+		})
+	}
+}
+
+func accountLink(baseURL, path, token string) string {
+	return strings.TrimRight(baseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func greetingName(firstName string) string {
+	if firstName == "" {
+		return "there"
+	}
+	return firstName
+}
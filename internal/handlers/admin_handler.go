@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/audit"
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CircuitBreakersResponse represents the state of every backend circuit breaker.
+// @Description Circuit breaker states
+type CircuitBreakersResponse struct {
+	Breakers []grpc.CircuitBreakerState `json:"breakers"`
+}
+
+// ListCircuitBreakers reports the state of every backend circuit breaker
+// @Summary List circuit breakers
+// @Description Reports the state of the circuit breaker for every backend service
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} CircuitBreakersResponse
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Router /api/v1/admin/circuit-breakers [get]
+func ListCircuitBreakers(breakers grpc.CircuitBreakers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		states := make([]grpc.CircuitBreakerState, 0, len(breakers))
+		for _, breaker := range breakers {
+			states = append(states, breaker.State())
+		}
+		sort.Slice(states, func(i, j int) bool {
+			return states[i].Service < states[j].Service
+		})
+
+		c.JSON(http.StatusOK, CircuitBreakersResponse{Breakers: states})
+	}
+}
+
+// AuditEventsResponse represents a page of audit events.
+// @Description Audit events, newest first
+type AuditEventsResponse struct {
+	Events []audit.Event `json:"events"`
+}
+
+// AuditVerificationResponse reports whether the audit chain is intact.
+// @Description Audit chain verification result
+type AuditVerificationResponse struct {
+	Intact bool   `json:"intact"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ListAuditEvents returns audit events matching the query
+// @Summary List audit events
+// @Description Lists audited admin changes and prescription uploads, newest first
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param actor_id query string false "Actor user ID"
+// @Param resource_id query string false "Target resource ID"
+// @Param route query string false "Route template, e.g. /api/v1/admin/products/:id"
+// @Param from query string false "Earliest timestamp (RFC 3339)"
+// @Param to query string false "Latest timestamp (RFC 3339)"
+// @Param limit query int false "Maximum number of events" default(100)
+// @Success 200 {object} AuditEventsResponse
+// @Failure 400 {object} utils.ErrorResponse "Bad Request"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 501 {object} utils.ErrorResponse "Not Implemented"
+// @Router /api/v1/admin/audit [get]
+func ListAuditEvents(auditSink audit.Sink) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := audit.Filter{
+			ActorID:    c.Query("actor_id"),
+			ResourceID: c.Query("resource_id"),
+			Route:      c.Query("route"),
+			Limit:      utils.GetIntQueryParam(c, "limit", 100),
+		}
+
+		for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+			value := c.Query(param)
+			if value == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				utils.RespondWithError(c, utils.ErrValidation, "Invalid timestamp", map[string]string{param: "Must be an RFC 3339 timestamp"})
+				return
+			}
+			*target = parsed
+		}
+
+		events, err := auditSink.Query(c.Request.Context(), filter)
+		if errors.Is(err, audit.ErrQueryNotSupported) {
+			utils.RespondWithError(c, utils.ErrNotImplemented, "The configured audit sink cannot be queried", nil)
+			return
+		}
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to query audit log", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to query audit log", nil)
+			return
+		}
+
+		if events == nil {
+			events = []audit.Event{}
+		}
+		c.JSON(http.StatusOK, AuditEventsResponse{Events: events})
+	}
+}
+
+// VerifyAuditLog checks the audit hash chain for tampering
+// @Summary Verify audit log
+// @Description Recomputes the audit hash chain and reports the first broken link, if any
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} AuditVerificationResponse
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 501 {object} utils.ErrorResponse "Not Implemented"
+// @Router /api/v1/admin/audit/verify [get]
+func VerifyAuditLog(auditSink audit.Sink) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verifier, ok := auditSink.(audit.Verifier)
+		if !ok {
+			utils.RespondWithError(c, utils.ErrNotImplemented, "The configured audit sink cannot be verified", nil)
+			return
+		}
+
+		if err := verifier.Verify(c.Request.Context()); err != nil {
+			utils.WarnContext(c.Request.Context(), "Audit chain verification failed", map[string]interface{}{
+				"error": err,
+			})
+			c.JSON(http.StatusOK, AuditVerificationResponse{Intact: false, Error: err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, AuditVerificationResponse{Intact: true})
+	}
+}
+
+// RBACPolicyResponse lists the permissions granted to each role.
+// @Description Active RBAC policy
+type RBACPolicyResponse struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// GetRBACPolicy returns the active RBAC policy
+// @Summary Get RBAC policy
+// @Description Lists the permissions the active policy grants to each role
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} RBACPolicyResponse
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Router /api/v1/admin/rbac/policy [get]
+func GetRBACPolicy(enforcer *rbac.Enforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, RBACPolicyResponse{Roles: enforcer.Policy().Roles()})
+	}
+}
+
+// ReloadRBACPolicy reloads the RBAC policy file
+// @Summary Reload RBAC policy
+// @Description Reloads the RBAC policy file. An invalid policy is rejected and the current one stays in effect.
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} RBACPolicyResponse
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /api/v1/admin/rbac/reload [post]
+func ReloadRBACPolicy(enforcer *rbac.Enforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := enforcer.Reload(); err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to reload RBAC policy", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to reload RBAC policy", map[string]string{"policy": err.Error()})
+			return
+		}
+
+		utils.InfoContext(c.Request.Context(), "Reloaded RBAC policy", map[string]interface{}{
+			"user_id": c.GetString("user_id"),
+		})
+
+		c.JSON(http.StatusOK, RBACPolicyResponse{Roles: enforcer.Policy().Roles()})
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/proto"
@@ -33,19 +34,15 @@ type RegisterRequest struct {
 // @Produce json
 // @Param request body RegisterRequest true "User registration details"
 // @Success 200 {object} proto.RegisterResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/register [post]
 func Register(authClient grpc.AuthClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req RegisterRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
@@ -67,32 +64,26 @@ func Register(authClient grpc.AuthClient) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			utils.Error("Failed to register user", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to register user", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to register user",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to register user")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to register user", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to register user", map[string]interface{}{
 				"error": resp.Message,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -112,29 +103,25 @@ type LoginRequest struct {
 
 // Login handles user login.
 // @Summary Login
-// @Description Login with the provided email/username and password
+// @Description Login with the provided email/username and password. For accounts with MFA enabled the response has mfa_required and an mfa_token instead of tokens; complete the login at /api/v1/login/mfa.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login details"
 // @Success 200 {object} proto.LoginResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/login [post]
 func Login(authClient grpc.AuthClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			utils.Error("Failed to bind JSON", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to bind JSON", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
@@ -146,43 +133,198 @@ func Login(authClient grpc.AuthClient) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			utils.Error("Failed to login", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to login", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to login",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to login")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to login", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to login", map[string]interface{}{
 				"error": resp.Message,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
+			return
+		}
+
+		// The account has MFA enabled; the client completes the login at
+		// /api/v1/login/mfa with the challenge token.
+		if resp.MfaRequired {
+			c.JSON(http.StatusOK, gin.H{
+				"success":      true,
+				"message":      resp.Message,
+				"mfa_required": true,
+				"mfa_token":    resp.MfaToken,
 			})
 			return
 		}
 
 		// Return the success response
 		c.JSON(http.StatusOK, gin.H{
-			"success":  true,
-			"message":  resp.Message,
-			"token":    resp.Token,
-			"user_id":  resp.UserId,
-			"username": resp.Username,
-			"role":     resp.Role,
+			"success":       true,
+			"message":       resp.Message,
+			"token":         resp.Token,
+			"refresh_token": resp.RefreshToken,
+			"expires_in":    resp.ExpiresIn,
+			"user_id":       resp.UserId,
+			"username":      resp.Username,
+			"role":          resp.Role,
+		})
+	}
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken exchanges a refresh token for a new token pair.
+// @Summary Refresh token
+// @Description Exchanges a refresh token for a new access token and refresh token. Refresh tokens are single use: reusing one revokes every token issued from it.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} proto.RefreshTokenResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/token/refresh [post]
+func RefreshToken(authClient grpc.AuthClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"refresh_token": "Refresh token is required"})
+			return
+		}
+
+		resp, err := authClient.RefreshToken(c.Request.Context(), &proto.RefreshTokenRequest{
+			RefreshToken: req.RefreshToken,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to refresh token", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to refresh token")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to refresh token", map[string]interface{}{
+				"error": resp.Message,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrAuth, resp.Message, nil)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":       true,
+			"message":       resp.Message,
+			"token":         resp.Token,
+			"refresh_token": resp.RefreshToken,
+			"expires_in":    resp.ExpiresIn,
+		})
+	}
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	AllSessions  bool   `json:"all_sessions"`
+}
+
+// Logout revokes the caller's tokens.
+// @Summary Logout
+// @Description Revokes the access token, and the refresh token if given. With all_sessions every token of the user is revoked.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param request body LogoutRequest false "Logout options"
+// @Success 200 {object} proto.LogoutResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/logout [post]
+func Logout(authClient grpc.AuthClient, revocations *grpc.RevocationList) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LogoutRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
+				return
+			}
+		}
+
+		userID := c.GetString("user_id")
+
+		resp, err := authClient.Logout(c.Request.Context(), &proto.LogoutRequest{
+			Token:        c.GetString("token"),
+			RefreshToken: req.RefreshToken,
+			AllSessions:  req.AllSessions,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to logout", map[string]interface{}{
+				"error":   err,
+				"user_id": userID,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to logout")
+			return
+		}
+
+		if !resp.Success {
+			utils.ErrorContext(c.Request.Context(), "Failed to logout", map[string]interface{}{
+				"error":   resp.Message,
+				"user_id": userID,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
+			return
+		}
+
+		// Reject the revoked tokens here right away instead of waiting for
+		// the next revocation sync.
+		if req.AllSessions {
+			revocations.RevokeUser(userID)
+		} else {
+			var expiresAt time.Time
+			if unix := c.GetInt64("token_expires_at"); unix > 0 {
+				expiresAt = time.Unix(unix, 0)
+			}
+			revocations.RevokeToken(c.GetString("token_id"), userID, expiresAt)
+		}
+
+		utils.InfoContext(c.Request.Context(), "User logged out", map[string]interface{}{
+			"user_id":      userID,
+			"all_sessions": req.AllSessions,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": resp.Message,
 		})
 	}
 }
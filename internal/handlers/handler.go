@@ -1,25 +1,268 @@
 package handlers
 
 import (
+	"errors"
+	"mime/multipart"
 	"net/http"
+	"sync/atomic"
 
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	ReadinessOK          = "ok"
+	ReadinessDegraded    = "degraded"
+	ReadinessUnavailable = "unavailable"
+	ReadinessShutdown    = "shutting_down"
+)
+
+// ready reports whether the gateway should keep receiving traffic. It is
+// flipped off at the start of a graceful shutdown so the load balancer stops
+// routing new requests before the server stops accepting connections.
+var ready atomic.Bool
+
+func init() {
+	ready.Store(true)
+}
+
+// SetReady marks the gateway as ready or not ready to receive traffic.
+func SetReady(value bool) {
+	ready.Store(value)
+}
+
 // HealthResponse represents the response for the health check endpoint.
 // @Description Health check response
 type HealthResponse struct {
 	Status string `json:"status" example:"ok"`
 }
 
+// ReadinessResponse represents the response for the readiness endpoint.
+// @Description Readiness check response
+type ReadinessResponse struct {
+	Status       string                  `json:"status" example:"ok"`
+	Dependencies []grpc.DependencyStatus `json:"dependencies,omitempty"`
+}
+
 // HealthCheck handles health check requests.
 // @Summary Health check
 // @Description Check if the service is running
 // @Tags Utility
 // @Produce json
 // @Success 200 {object} HealthResponse
-// @Error 500 {object} ErrorResponse
+// @Failure 503 {object} HealthResponse
 // @Router /health [get]
 func HealthCheck(c *gin.Context) {
+	if !ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: ReadinessShutdown})
+		return
+	}
+
 	c.JSON(http.StatusOK, HealthResponse{Status: "good"})
 }
+
+// Liveness handles liveness probes.
+// @Summary Liveness check
+// @Description Check if the gateway process is alive, without probing backends
+// @Tags Utility
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /livez [get]
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: ReadinessOK})
+}
+
+// Readiness handles readiness probes.
+// @Summary Readiness check
+// @Description Probe every backend service and report whether the gateway can serve traffic
+// @Tags Utility
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func Readiness(healthChecker grpc.HealthChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ready.Load() {
+			c.JSON(http.StatusServiceUnavailable, ReadinessResponse{Status: ReadinessShutdown})
+			return
+		}
+
+		dependencies := healthChecker.Check(c.Request.Context())
+
+		verdict := ReadinessOK
+		for _, dep := range dependencies {
+			if dep.Status == grpc.HealthStatusUp {
+				continue
+			}
+			if dep.Critical {
+				verdict = ReadinessUnavailable
+				break
+			}
+			verdict = ReadinessDegraded
+		}
+
+		statusCode := http.StatusOK
+		if verdict == ReadinessUnavailable {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, ReadinessResponse{
+			Status:       verdict,
+			Dependencies: dependencies,
+		})
+	}
+}
+
+// ListErrorCodes returns the catalog of errors the gateway can return.
+// @Summary List error codes
+// @Description Lists every error code the gateway can return, with its problem type URI
+// @Tags Utility
+// @Produce json
+// @Success 200 {array} utils.ErrorCode
+// @Router /problems [get]
+func ListErrorCodes(c *gin.Context) {
+	c.JSON(http.StatusOK, utils.ErrorCatalog)
+}
+
+// GetErrorCode documents a single problem type.
+// @Summary Get error code
+// @Description Describes the problem type referenced by the "type" URI of a problem+json response
+// @Tags Utility
+// @Produce json
+// @Param code path string true "Problem type, e.g. validation-error"
+// @Success 200 {object} utils.ErrorCode
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /problems/{code} [get]
+func GetErrorCode(c *gin.Context) {
+	errorCode, ok := utils.LookupErrorCode(c.Param("code"))
+	if !ok {
+		utils.RespondWithError(c, utils.ErrNotFound, "Unknown error code", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, errorCode)
+}
+
+// customerFilter returns the customer ID that backend lookups are restricted
+// to: the caller's own, or "admin", which the backends treat as any customer,
+// when the caller holds the route's permission for any resource.
+func customerFilter(c *gin.Context) string {
+	if c.GetString(rbac.ScopeKey) == rbac.ScopeAny {
+		return "admin"
+	}
+	return c.GetString("user_id")
+}
+
+// maxFormOverhead is the room left for the other form fields and the
+// multipart framing on top of the size limit of an uploaded file.
+const maxFormOverhead = 1 << 20
+
+// limitUploadRequest caps the body of a request that may carry a file in
+// field at the policy's size limit plus form overhead, and parses the form,
+// so an oversized upload is refused once it passes the cap rather than after
+// it has been read in full. Other form errors are left to the handler.
+func limitUploadRequest(c *gin.Context, field string, policy upload.Policy) bool {
+	limit := policy.MaxSize + maxFormOverhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	if c.ContentType() != gin.MIMEMultipartPOSTForm {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if err := c.Request.ParseMultipartForm(limit); errors.As(err, &tooLarge) {
+		return checkedUpload(c, field, "", upload.TooLarge(field, policy))
+	}
+	return true
+}
+
+// validateUpload checks the file uploaded in field against the policy,
+// responding with the reason when it is refused.
+func validateUpload(c *gin.Context, field string, header *multipart.FileHeader, policy upload.Policy) (*upload.File, bool) {
+	file, err := upload.Validate(field, header, policy)
+	if !checkedUpload(c, field, header.Filename, err) {
+		return nil, false
+	}
+	return file, true
+}
+
+// checkedUpload responds with the reason an upload failed validation, and
+// reports whether it passed.
+func checkedUpload(c *gin.Context, field, filename string, err error) bool {
+	var invalid *upload.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WarnContext(c.Request.Context(), "Rejected upload", map[string]interface{}{
+			"field":    field,
+			"filename": filename,
+			"reason":   invalid.Reason,
+		})
+		utils.RespondWithError(c, utils.ErrValidation, "Invalid file", invalid.Details())
+		return false
+	}
+	if err != nil {
+		utils.ErrorContext(c.Request.Context(), "Failed to read upload", map[string]interface{}{
+			"error": err,
+			"field": field,
+		})
+		utils.RespondWithError(c, utils.ErrInternal, "Failed to read upload", nil)
+		return false
+	}
+
+	return true
+}
+
+// redeemUpload claims the confirmed direct upload whose token was submitted
+// in field, responding with the reason when it is refused. The receipt is
+// redeemed once the upload has been claimed, and the claim must be settled
+// with the outcome of the request.
+func redeemUpload(c *gin.Context, target *DirectUpload, receipts *upload.Receipts, field, token string) (*uploadClaim, bool) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+
+	staged, err := receipts.Open(token, target.purpose, userID)
+	var claimed string
+	if err == nil {
+		claimed, err = target.claim(ctx, staged)
+	}
+	if err == nil {
+		// Another request may have redeemed the receipt in the meantime
+		if _, err = receipts.Redeem(token, target.purpose, userID); err != nil {
+			target.store.Delete(ctx, claimed)
+		}
+	}
+
+	switch {
+	case err == nil:
+		return &uploadClaim{Key: claimed, target: target, receipts: receipts, token: token, staged: staged}, true
+	case errors.Is(err, upload.ErrInvalidReceipt), errors.Is(err, storage.ErrNotFound):
+		utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{field: "Upload token is invalid or has expired"})
+	default:
+		utils.ErrorContext(ctx, "Failed to claim upload", map[string]interface{}{
+			"error": err,
+			"field": field,
+		})
+		utils.RespondWithError(c, utils.ErrInternal, "Failed to store upload", nil)
+	}
+	return nil, false
+}
+
+// scanUpload runs the file uploaded in field through the malware scanner,
+// responding with the reason when it is refused.
+func scanUpload(c *gin.Context, guard *scan.Guard, field, purpose string, file *upload.File) bool {
+	err := guard.Check(c.Request.Context(), purpose, file)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, scan.ErrInfected):
+		utils.RespondWithError(c, utils.ErrValidation, "Invalid file", map[string]string{field: "File was flagged by the malware scanner"})
+	case errors.Is(err, scan.ErrUnavailable):
+		utils.RespondWithError(c, utils.ErrUnavailable, "File could not be scanned, please try again later", nil)
+	default:
+		utils.RespondWithError(c, utils.ErrInternal, "Failed to scan upload", nil)
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimitUploadRequest(t *testing.T) {
+	policy := upload.Policy{MaxSize: 1 << 10, Types: []string{upload.TypePNG}}
+
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"within limit", 1 << 10, http.StatusOK},
+		{"over file limit within form overhead", 2 << 10, http.StatusOK},
+		{"over request limit", maxFormOverhead + 2<<10, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("items", "[]")
+		file, _ := form.CreateFormFile("prescription", "scan.png")
+		file.Write(bytes.Repeat([]byte{0}, tt.size))
+		form.Close()
+
+		r := gin.New()
+		r.POST("/upload", func(c *gin.Context) {
+			if limitUploadRequest(c, "prescription", policy) {
+				c.Status(http.StatusOK)
+			}
+		})
+		req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "larger than the limit of 1 KB") {
+			t.Errorf("%s: got body %s, want the size limit", tt.name, rec.Body)
+		}
+	}
+}
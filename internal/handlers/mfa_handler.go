@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MFALimiter limits attempts to complete an MFA login, both per client IP and
+// per challenge token, so a code cannot be guessed by spreading attempts over
+// many addresses. Like AccountLimiter, the counts are kept per replica.
+type MFALimiter struct {
+	perIP    *utils.RateLimiter
+	perToken *utils.RateLimiter
+}
+
+func NewMFALimiter(cfg config.MFAConfig) *MFALimiter {
+	return &MFALimiter{
+		perIP:    utils.NewRateLimiter(cfg.AttemptsPerIP, cfg.AttemptWindow),
+		perToken: utils.NewRateLimiter(cfg.AttemptsPerToken, cfg.AttemptWindow),
+	}
+}
+
+// allow reports whether the attempt may proceed, responding with 429 when it
+// may not. Challenge tokens are hashed so the limiter does not hold them.
+func (l *MFALimiter) allow(c *gin.Context, mfaToken string) bool {
+	ok, retryAfter := l.perIP.Allow(c.ClientIP())
+	if ok {
+		sum := sha256.Sum256([]byte(mfaToken))
+		ok, retryAfter = l.perToken.Allow(hex.EncodeToString(sum[:]))
+	}
+	if ok {
+		return true
+	}
+
+	respondRateLimited(c, retryAfter)
+	return false
+}
+
+type VerifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// VerifyMFA completes a login that required a second factor.
+// @Summary Complete MFA login
+// @Description Completes a login that returned mfa_required, with a TOTP code or a recovery code. Recovery codes work once.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyMFARequest true "Challenge token and code"
+// @Success 200 {object} proto.VerifyMFAResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/login/mfa [post]
+func VerifyMFA(authClient grpc.AuthClient, limiter *MFALimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req VerifyMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "") == (req.RecoveryCode == "") {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": "mfa_token and either code or recovery_code are required"})
+			return
+		}
+
+		if !limiter.allow(c, req.MFAToken) {
+			return
+		}
+
+		resp, err := authClient.VerifyMFA(c.Request.Context(), &proto.VerifyMFARequest{
+			MfaToken:     req.MFAToken,
+			Code:         req.Code,
+			RecoveryCode: req.RecoveryCode,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to verify MFA code", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to verify MFA code")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to verify MFA code", map[string]interface{}{
+				"error": resp.Message,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrAuth, resp.Message, nil)
+			return
+		}
+
+		fields := map[string]interface{}{
+			"user_id": resp.UserId,
+		}
+		if req.RecoveryCode != "" {
+			fields["codes_remaining"] = resp.RecoveryCodesRemaining
+			utils.WarnContext(c.Request.Context(), "Login completed with a recovery code", fields)
+		} else {
+			utils.InfoContext(c.Request.Context(), "Login completed with MFA", fields)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":                  true,
+			"message":                  resp.Message,
+			"token":                    resp.Token,
+			"refresh_token":            resp.RefreshToken,
+			"expires_in":               resp.ExpiresIn,
+			"user_id":                  resp.UserId,
+			"username":                 resp.Username,
+			"role":                     resp.Role,
+			"recovery_codes_remaining": resp.RecoveryCodesRemaining,
+		})
+	}
+}
+
+// EnrollMFA starts TOTP enrollment.
+// @Summary Enroll in MFA
+// @Description Creates a TOTP secret for the user. Render provisioning_uri as a QR code for an authenticator app, then confirm with a code from it.
+// @Tags MFA
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} proto.EnrollMFAResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/mfa/enroll [post]
+func EnrollMFA(authClient grpc.AuthClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := authClient.EnrollMFA(c.Request.Context(), &proto.EnrollMFARequest{
+			Token: c.GetString("token"),
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to enroll in MFA", map[string]interface{}{
+				"error":   err,
+				"user_id": c.GetString("user_id"),
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to enroll in MFA")
+			return
+		}
+
+		if !resp.Success {
+			utils.ErrorContext(c.Request.Context(), "Failed to enroll in MFA", map[string]interface{}{
+				"error":   resp.Message,
+				"user_id": c.GetString("user_id"),
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":          true,
+			"message":          resp.Message,
+			"secret":           resp.Secret,
+			"provisioning_uri": resp.ProvisioningUri,
+		})
+	}
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ConfirmMFA finishes TOTP enrollment.
+// @Summary Confirm MFA enrollment
+// @Description Enables MFA once a code from the authenticator app is confirmed, and returns the recovery codes. They are only shown once.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param request body MFACodeRequest true "TOTP code"
+// @Success 200 {object} proto.ConfirmMFAResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/mfa/confirm [post]
+func ConfirmMFA(authClient grpc.AuthClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"code": "Code is required"})
+			return
+		}
+
+		userID := c.GetString("user_id")
+
+		resp, err := authClient.ConfirmMFA(c.Request.Context(), &proto.ConfirmMFARequest{
+			Token: c.GetString("token"),
+			Code:  req.Code,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to confirm MFA", map[string]interface{}{
+				"error":   err,
+				"user_id": userID,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to confirm MFA")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to confirm MFA", map[string]interface{}{
+				"error":   resp.Message,
+				"user_id": userID,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
+			return
+		}
+
+		utils.InfoContext(c.Request.Context(), "MFA enabled", map[string]interface{}{
+			"user_id": userID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"message":        resp.Message,
+			"recovery_codes": resp.RecoveryCodes,
+		})
+	}
+}
+
+// DisableMFA turns MFA off.
+// @Summary Disable MFA
+// @Description Disables MFA for the user after checking a TOTP or recovery code.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param request body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} proto.DisableMFAResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/mfa/disable [post]
+func DisableMFA(authClient grpc.AuthClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"code": "Code is required"})
+			return
+		}
+
+		userID := c.GetString("user_id")
+
+		resp, err := authClient.DisableMFA(c.Request.Context(), &proto.DisableMFARequest{
+			Token: c.GetString("token"),
+			Code:  req.Code,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to disable MFA", map[string]interface{}{
+				"error":   err,
+				"user_id": userID,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to disable MFA")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to disable MFA", map[string]interface{}{
+				"error":   resp.Message,
+				"user_id": userID,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
+			return
+		}
+
+		utils.WarnContext(c.Request.Context(), "MFA disabled", map[string]interface{}{
+			"user_id": userID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": resp.Message,
+		})
+	}
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes.
+// @Summary Regenerate recovery codes
+// @Description Replaces every recovery code of the user after checking a TOTP code. The new codes are only shown once.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param request body MFACodeRequest true "TOTP code"
+// @Success 200 {object} proto.RegenerateRecoveryCodesResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(authClient grpc.AuthClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"code": "Code is required"})
+			return
+		}
+
+		userID := c.GetString("user_id")
+
+		resp, err := authClient.RegenerateRecoveryCodes(c.Request.Context(), &proto.RegenerateRecoveryCodesRequest{
+			Token: c.GetString("token"),
+			Code:  req.Code,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to regenerate recovery codes", map[string]interface{}{
+				"error":   err,
+				"user_id": userID,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to regenerate recovery codes")
+			return
+		}
+
+		if !resp.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to regenerate recovery codes", map[string]interface{}{
+				"error":   resp.Message,
+				"user_id": userID,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
+			return
+		}
+
+		utils.InfoContext(c.Request.Context(), "Recovery codes regenerated", map[string]interface{}{
+			"user_id": userID,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"message":        resp.Message,
+			"recovery_codes": resp.RecoveryCodes,
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeMFAClient rejects every code and counts the attempts it saw.
+type fakeMFAClient struct {
+	grpc.AuthClient
+
+	attempts int
+}
+
+func (f *fakeMFAClient) VerifyMFA(ctx context.Context, req *proto.VerifyMFARequest) (*proto.VerifyMFAResponse, error) {
+	f.attempts++
+	return &proto.VerifyMFAResponse{Message: "Invalid code"}, nil
+}
+
+func TestVerifyMFALimitsAttemptsPerToken(t *testing.T) {
+	client := &fakeMFAClient{}
+	limiter := NewMFALimiter(config.MFAConfig{AttemptsPerIP: 100, AttemptsPerToken: 3, AttemptWindow: time.Minute})
+	r := gin.New()
+	r.POST("/login/mfa", VerifyMFA(client, limiter))
+
+	attempt := func(token string, i int) int {
+		req := httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(`{"mfa_token":"`+token+`","code":"123456"}`))
+		req.Header.Set("Content-Type", "application/json")
+		// Every attempt comes from another address.
+		req.RemoteAddr = "192.0.2." + strconv.Itoa(i) + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 1; i <= 3; i++ {
+		if status := attempt("challenge-1", i); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: got status %d, want 401", i, status)
+		}
+	}
+	if status := attempt("challenge-1", 4); status != http.StatusTooManyRequests {
+		t.Errorf("attempt over the token limit: got status %d, want 429", status)
+	}
+	if client.attempts != 3 {
+		t.Errorf("auth service saw %d attempts, want 3", client.attempts)
+	}
+
+	if status := attempt("challenge-2", 5); status != http.StatusUnauthorized {
+		t.Errorf("other challenge: got status %d, want 401", status)
+	}
+}
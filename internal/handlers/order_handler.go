@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
 
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/PharmaKart/gateway-svc/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -25,64 +27,44 @@ type OrderRequest struct {
 
 type Order struct {
 	OrderRequest
-	Prescription *multipart.FileHeader `form:"prescription" swaggerignore:"true"`
-}
-
-// ErrorResponse represents an error response from the API
-// @Description Error response
-type ErrorResponse struct {
-	Type    string            `json:"type" example:"VALIDATION_ERROR"`
-	Message string            `json:"message" example:"Invalid request format"`
-	Details map[string]string `json:"details,omitempty" example:"field:error message"`
+	Prescription       *multipart.FileHeader `form:"prescription" swaggerignore:"true"`
+	PrescriptionUpload string                `form:"prescription_upload"`
 }
 
 // @Description Order placement request
 type SwaggerOrderRequest struct {
-	Items        []OrderItem `json:"items"`
-	Prescription string      `json:"prescription" format:"binary"`
+	Items              []OrderItem `json:"items"`
+	Prescription       string      `json:"prescription" format:"binary"`
+	PrescriptionUpload string      `json:"prescription_upload"`
 }
 
 // PlaceOrder creates a new order
 // @Summary Place a new order
-// @Description Creates new order with the given product ID and quantity
+// @Description Creates new order with the given product ID and quantity. Orders with prescription-only products are held for pharmacist review (review_required) and get a payment URL only once the prescription is approved.
 // @Tags Orders
 // @Accept multipart/form-data
 // @Produce json
 // @Security ApiKeyAuth
 // @Param Authorization header string true "Bearer token"
 // @Param items formData string true "Order Items JSON"
-// @Param prescription formData file false "Prescription Image"
+// @Param prescription formData file false "Prescription (JPEG, PNG or PDF)"
+// @Param prescription_upload formData string false "Upload token of a confirmed direct upload, instead of prescription"
 // @Success 200 {object} proto.PlaceOrderResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/orders [post]
-func PlaceOrder(cfg *config.Config, orderClient grpc.OrderClient) gin.HandlerFunc {
+func PlaceOrder(cfg *config.Config, prescriptions storage.BlobStore, guard *scan.Guard, receipts *upload.Receipts, orderClient grpc.OrderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
-			return
-		}
-
 		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
-		if userRole == "admin" {
-			c.JSON(http.StatusForbidden, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "Admins cannot place orders",
-			})
+		policy := upload.PrescriptionPolicy(cfg.Upload)
+		if !limitUploadRequest(c, "prescription", policy) {
 			return
 		}
 
@@ -98,21 +80,13 @@ func PlaceOrder(cfg *config.Config, orderClient grpc.OrderClient) gin.HandlerFun
 
 		// Unmarshal the JSON string into the temporary struct
 		if err := json.Unmarshal([]byte(itemsStr), &tempRequest); err != nil {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
 		// Check if items are provided
 		if len(tempRequest.Items) == 0 {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"items": "At least one item is required"},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"items": "At least one item is required"})
 			return
 		}
 
@@ -122,34 +96,51 @@ func PlaceOrder(cfg *config.Config, orderClient grpc.OrderClient) gin.HandlerFun
 		// Handle prescription file separately
 		file, _ := c.FormFile("prescription")
 		req.Prescription = file
+		req.PrescriptionUpload = c.PostForm("prescription_upload")
 
-		var prescriptionURL *string
+		if req.Prescription != nil && req.PrescriptionUpload != "" {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"prescription_upload": "Send either a prescription or an upload token, not both"})
+			return
+		}
+
+		var prescriptionKey *string
+		var claim *uploadClaim
+
+		// A prescription uploaded directly to storage was checked when confirmed
+		if req.PrescriptionUpload != "" {
+			var ok bool
+			claim, ok = redeemUpload(c, PrescriptionUploads(cfg, prescriptions), receipts, "prescription_upload", req.PrescriptionUpload)
+			if !ok {
+				return
+			}
+			prescriptionKey = &claim.Key
+		}
 
 		// Check if a prescription is provided
 		if req.Prescription != nil {
-			// Validate file type
-			allowedExtensions := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".pdf": true}
-			ext := filepath.Ext(req.Prescription.Filename)
-			if !allowedExtensions[ext] {
-				c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-					Type:    "VALIDATION_ERROR",
-					Message: "Invalid file format",
-					Details: map[string]string{"format": "Only JPG, JPEG, PNG, and PDF files are allowed"},
-				})
+			prescription, ok := validateUpload(c, "prescription", req.Prescription, policy)
+			if !ok {
+				return
+			}
+			if !scanUpload(c, guard, "prescription", UploadPurposePrescriptions, prescription) {
 				return
 			}
 
-			// Upload prescription to S3
-			url, err := utils.UploadImageToS3(c, cfg, "prescriptions", req.Prescription)
+			// Upload prescription to private storage
+			key, err := storage.Upload(c.Request.Context(), prescriptions, "prescriptions", prescription, storage.PutOptions{
+				Private:              true,
+				ServerSideEncryption: cfg.Prescriptions.ServerSideEncryption,
+				KMSKeyID:             cfg.Prescriptions.KMSKeyID,
+			})
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-					Type:    "INTERNAL_ERROR",
-					Message: "Failed to upload prescription",
+				utils.ErrorContext(c.Request.Context(), "Failed to upload prescription", map[string]interface{}{
+					"error": err,
 				})
+				utils.RespondWithError(c, utils.ErrInternal, "Failed to upload prescription", nil)
 				return
 			}
 
-			prescriptionURL = &url
+			prescriptionKey = &key
 		}
 
 		// Convert order items to gRPC format
@@ -166,36 +157,31 @@ func PlaceOrder(cfg *config.Config, orderClient grpc.OrderClient) gin.HandlerFun
 		resp, err := orderClient.PlaceOrder(c.Request.Context(), &proto.PlaceOrderRequest{
 			CustomerId:      customerID.(string),
 			Items:           orderItems,
-			PrescriptionUrl: prescriptionURL,
+			PrescriptionKey: prescriptionKey,
 		})
+		claim.settle(c.Request.Context(), err, resp.GetSuccess())
 		if err != nil {
-			utils.Error("Failed to place order", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to place order", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to place order",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to place order")
 			return
 		}
 
 		// Check if the response indicates a failure
 		if !resp.Success {
-			utils.Error("Failed to place order", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to place order", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to place order",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to place order", nil)
 			return
 		}
 
@@ -205,7 +191,7 @@ func PlaceOrder(cfg *config.Config, orderClient grpc.OrderClient) gin.HandlerFun
 
 // GenerateNewPaymentUrl generates a new payment URL for an order
 // @Summary Generate a new payment URL
-// @Description Generates a new payment URL for an order
+// @Description Generates a new payment URL for an order. Orders awaiting prescription review, or whose prescription was rejected, cannot be paid for.
 // @Tags Orders
 // @Accept json
 // @Produce json
@@ -213,73 +199,55 @@ func PlaceOrder(cfg *config.Config, orderClient grpc.OrderClient) gin.HandlerFun
 // @Param Authorization header string true "Bearer token"
 // @Param id path string true "Order ID"
 // @Success 200 {object} proto.GeneratePaymentURLResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/orders/{id}/payment [post]
 func GenerateNewPaymentUrl(orderClient grpc.OrderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		var customerID string
+		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
-		userId, ok := c.Get("user_id")
+		orderID := c.Param("id")
+
+		order, ok := getOrder(c, orderClient, customerID.(string))
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
 			return
 		}
-
-		customerID = userId.(string)
-		if userRole == "admin" {
-			c.JSON(http.StatusForbidden, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "Admins cannot generate payment URLs for customers",
-			})
+		if isHeldForReview(order.Status) {
+			utils.RespondWithError(c, utils.ErrConflict, "Order cannot be paid for until its prescription is approved", map[string]string{"status": order.Status})
 			return
 		}
 
-		orderID := c.Param("id")
-
 		resp, err := orderClient.GenerateNewPaymentUrl(c.Request.Context(), &proto.GenerateNewPaymentUrlRequest{
 			OrderId:    orderID,
-			CustomerId: customerID,
+			CustomerId: customerID.(string),
 		})
 		if err != nil {
-			utils.Error("Failed to generate payment URL", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to generate payment URL", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to generate payment URL",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to generate payment URL")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to generate payment URL", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to generate payment URL", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to generate payment URL",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to generate payment URL", nil)
 			return
 		}
 
@@ -297,36 +265,14 @@ func GenerateNewPaymentUrl(orderClient grpc.OrderClient) gin.HandlerFunc {
 // @Param Authorization header string true "Bearer token"
 // @Param id path string true "Order ID"
 // @Success 200 {object} proto.GetOrderResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/orders/{id} [get]
 func GetOrder(orderClient grpc.OrderClient, paymentClient grpc.PaymentClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		var customerID string
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
-			return
-		}
-
-		userId, ok := c.Get("user_id")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
-			return
-		}
-
-		customerID = userId.(string)
-		if userRole == "admin" {
-			customerID = "admin"
-		}
+		customerID := customerFilter(c)
 
 		orderID := c.Param("id")
 
@@ -335,44 +281,38 @@ func GetOrder(orderClient grpc.OrderClient, paymentClient grpc.PaymentClient) gi
 			CustomerId: customerID,
 		})
 		if err != nil {
-			utils.Error("Failed to get order", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get order", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get order",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get order")
 			return
 		}
 
 		// Check if the response indicates a failure
 		if !orderResp.Success {
-			utils.Error("Failed to get order", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get order", map[string]interface{}{
 				"error": orderResp,
 			})
 
 			if orderResp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(orderResp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get order",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get order", nil)
 			return
 		}
 		orderData, err := json.Marshal(orderResp)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to marshal order data"})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to marshal order data", nil)
 			return
 		}
 
 		var response map[string]interface{}
 		if err := json.Unmarshal(orderData, &response); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unmarshal order data"})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to unmarshal order data", nil)
 			return
 		}
 
@@ -407,18 +347,15 @@ func GetOrder(orderClient grpc.OrderClient, paymentClient grpc.PaymentClient) gi
 // @Param filter_operator query string false "Filter operator"
 // @Param filter_value query string false "Filter value"
 // @Success 200 {object} proto.ListCustomersOrdersResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/orders [get]
 func ListCustomersOrders(orderClient grpc.OrderClient) gin.HandlerFunc {
 	return func(c *gin.Context) { // Get customer ID from the token
 		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
@@ -449,33 +386,27 @@ func ListCustomersOrders(orderClient grpc.OrderClient) gin.HandlerFunc {
 			Limit:      int32(limit),
 		})
 		if err != nil {
-			utils.Error("Failed to list orders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to list orders", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to list orders",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to list orders")
 			return
 		}
 
 		// Check if the response indicates a failure
 		if !resp.Success {
-			utils.Error("Failed to list orders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to list orders", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to list orders",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to list orders", nil)
 			return
 		}
 
@@ -499,10 +430,10 @@ func ListCustomersOrders(orderClient grpc.OrderClient) gin.HandlerFunc {
 // @Param filter_operator query string false "Filter operator"
 // @Param filter_value query string false "Filter value"
 // @Success 200 {object} proto.ListAllOrdersResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/admin/orders [get]
 func ListAllOrders(orderClient grpc.OrderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -532,33 +463,27 @@ func ListAllOrders(orderClient grpc.OrderClient) gin.HandlerFunc {
 			Limit:     int32(limit),
 		})
 		if err != nil {
-			utils.Error("Failed to list orders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to list orders", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to list orders",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to list orders")
 			return
 		}
 
 		// Check if the response indicates a failure
 		if !resp.Success {
-			utils.Error("Failed to list all orders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to list all orders", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to list orders",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to list orders", nil)
 			return
 		}
 
@@ -572,7 +497,7 @@ type OrderStatusRequest struct {
 
 // UpdateOrder updates an order by ID
 // @Summary Update an order
-// @Description Updates an order by ID
+// @Description Updates an order by ID. Orders awaiting prescription review, or whose prescription was rejected, cannot be updated.
 // @Tags Orders
 // @Accept json
 // @Produce json
@@ -581,83 +506,69 @@ type OrderStatusRequest struct {
 // @Param id path string true "Order ID"
 // @Param request body OrderStatusRequest true "Order Details"
 // @Success 200 {object} proto.UpdateOrderStatusResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/admin/orders/{id} [put]
 func UpdateOrderStatus(orderClient grpc.OrderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		var customerID string
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
+		customerID := customerFilter(c)
+
+		orderID := c.Param("id")
+
+		var req OrderStatusRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
-		userId, ok := c.Get("user_id")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+		if isReviewDecision(req.Status) {
+			utils.RespondWithError(c, utils.ErrForbidden, "Prescriptions can only be approved or rejected through the review endpoints", map[string]string{"status": req.Status})
 			return
 		}
 
-		customerID = userId.(string)
-		if userRole == "admin" {
-			customerID = "admin"
+		order, ok := getOrder(c, orderClient, customerID)
+		if !ok {
+			return
 		}
-
-		orderID := c.Param("id")
-
-		var req OrderStatusRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+		if isHeldForReview(order.Status) {
+			utils.RespondWithError(c, utils.ErrConflict, "Order is held for prescription review", map[string]string{"status": order.Status})
 			return
 		}
 
+		// The order service refuses the update if the status changed since
+		// the order was read.
 		resp, err := orderClient.UpdateOrderStatus(c.Request.Context(), &proto.UpdateOrderStatusRequest{
-			OrderId:    orderID,
-			CustomerId: customerID,
-			Status:     req.Status,
+			OrderId:        orderID,
+			CustomerId:     customerID,
+			Status:         req.Status,
+			ExpectedStatus: order.Status,
 		})
 		if err != nil {
-			utils.Error("Failed to update order status", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update order status", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to update order",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to update order")
 			return
 		}
 
 		// Check if the response indicates a failure
 		if !resp.Success {
-			utils.Error("Failed to update order status", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update order status", map[string]interface{}{
 				"error": resp.Message,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
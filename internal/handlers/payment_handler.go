@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/proto"
@@ -36,14 +37,10 @@ func HandleWebhook(cfg *config.Config, paymentClient grpc.PaymentClient) gin.Han
 		// Read the body with max bytes limit
 		payload, err := io.ReadAll(io.LimitReader(reader, MaxBodyBytes))
 		if err != nil {
-			utils.Error("Error reading request body", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Error reading request body", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusServiceUnavailable, utils.ErrorResponse{
-				Type:    "SERVICE_UNAVAILABLE",
-				Message: "Error reading request body",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrUnavailable, "Error reading request body", map[string]string{"error": err.Error()})
 			return
 		}
 
@@ -54,33 +51,34 @@ func HandleWebhook(cfg *config.Config, paymentClient grpc.PaymentClient) gin.Han
 		// with the webhook signing key.
 		event, err := webhook.ConstructEvent(payload, c.GetHeader("Stripe-Signature"), endpointSecret)
 		if err != nil {
-			utils.Error("Error verifying webhook signature", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Error verifying webhook signature", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Error verifying webhook signature",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Error verifying webhook signature", map[string]string{"error": err.Error()})
 			return
 		}
 
+		handled := true
+
 		// Unmarshal the event data into an appropriate struct depending on its Type
 		switch event.Type {
 		case "checkout.session.async_payment_failed":
-			handleAsyncPaymentFailed(event, paymentClient)
+			handleAsyncPaymentFailed(c.Request.Context(), event, paymentClient)
 		case "charge.succeeded":
-			handleAsyncPaymentSucceeded(event)
+			handleAsyncPaymentSucceeded(c.Request.Context(), event)
 		case "checkout.session.completed":
-			handleCheckoutSessionCompleted(event, paymentClient)
+			handleCheckoutSessionCompleted(c.Request.Context(), event, paymentClient)
 		case "checkout.session.expired":
-			handleCheckoutSessionExpired(event, paymentClient)
+			handleCheckoutSessionExpired(c.Request.Context(), event, paymentClient)
 		default:
-			utils.Warn("Unhandled event type", map[string]interface{}{
+			handled = false
+			utils.WarnContext(c.Request.Context(), "Unhandled event type", map[string]interface{}{
 				"event": event.Type,
 			})
 		}
 
+		utils.WebhookEventsTotal.WithLabelValues(event.Type, strconv.FormatBool(handled)).Inc()
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"message": "Webhook processed successfully",
@@ -89,13 +87,13 @@ func HandleWebhook(cfg *config.Config, paymentClient grpc.PaymentClient) gin.Han
 }
 
 // Handler functions for different event types
-func handleAsyncPaymentFailed(event stripe.Event, paymentClient grpc.PaymentClient) {
+func handleAsyncPaymentFailed(ctx context.Context, event stripe.Event, paymentClient grpc.PaymentClient) {
 	// Handle async payment failed
-	utils.Warn("Handling async payment failed event", map[string]interface{}{
+	utils.WarnContext(ctx, "Handling async payment failed event", map[string]interface{}{
 		"event": event.ID,
 	})
 
-	_, err := paymentClient.StorePayment(context.Background(), &proto.StorePaymentRequest{
+	_, err := paymentClient.StorePayment(ctx, &proto.StorePaymentRequest{
 		TransactionId: event.ID,
 		OrderId:       event.Data.Object["client_reference_id"].(string),
 		CustomerId:    event.Data.Object["customer"].(string),
@@ -104,14 +102,14 @@ func handleAsyncPaymentFailed(event stripe.Event, paymentClient grpc.PaymentClie
 	})
 
 	if err != nil {
-		utils.Error("Failed to store failed payment", map[string]interface{}{
+		utils.ErrorContext(ctx, "Failed to store failed payment", map[string]interface{}{
 			"error": err,
 			"event": event.ID,
 		})
 	}
 }
 
-func handleAsyncPaymentSucceeded(event stripe.Event) *string {
+func handleAsyncPaymentSucceeded(ctx context.Context, event stripe.Event) *string {
 	// Handle async payment succeeded
 
 	var receiptUrl *string
@@ -120,12 +118,12 @@ func handleAsyncPaymentSucceeded(event stripe.Event) *string {
 	if ok {
 		receiptUrl = &receiptURL
 	} else {
-		utils.Warn("Receipt URL not found in event data", map[string]interface{}{
+		utils.WarnContext(ctx, "Receipt URL not found in event data", map[string]interface{}{
 			"event": event.ID,
 		})
 	}
 
-	utils.Info("Handling async payment succeeded event", map[string]interface{}{
+	utils.InfoContext(ctx, "Handling async payment succeeded event", map[string]interface{}{
 		"event":       event.ID,
 		"receipt_url": receiptUrl,
 	})
@@ -133,8 +131,8 @@ func handleAsyncPaymentSucceeded(event stripe.Event) *string {
 	return receiptUrl
 }
 
-func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.PaymentClient) {
-	utils.Info("Handling checkout session completed event", map[string]interface{}{
+func handleCheckoutSessionCompleted(ctx context.Context, event stripe.Event, paymentClient grpc.PaymentClient) {
+	utils.InfoContext(ctx, "Handling checkout session completed event", map[string]interface{}{
 		"event": event.ID,
 	})
 	metadata := event.Data.Object["metadata"].(map[string]interface{})
@@ -143,7 +141,7 @@ func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.Payme
 	orderID, ok := metadata["order_id"].(string)
 	if !ok {
 		// Handle error or log missing metadata
-		utils.Warn("Order ID not found in metadata", map[string]interface{}{
+		utils.WarnContext(ctx, "Order ID not found in metadata", map[string]interface{}{
 			"event": event.ID,
 		})
 		return
@@ -152,7 +150,7 @@ func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.Payme
 	customerID, ok := metadata["customer_id"].(string)
 	if !ok {
 		// Handle error or log missing metadata
-		utils.Warn("Customer ID not found in metadata", map[string]interface{}{
+		utils.WarnContext(ctx, "Customer ID not found in metadata", map[string]interface{}{
 			"event": event.ID,
 		})
 		return
@@ -161,7 +159,7 @@ func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.Payme
 	amount, ok := event.Data.Object["amount_total"].(float64)
 	if !ok {
 		// Handle error or log missing amount
-		utils.Warn("Amount not found in event data", map[string]interface{}{
+		utils.WarnContext(ctx, "Amount not found in event data", map[string]interface{}{
 			"event": event.ID,
 		})
 		return
@@ -170,14 +168,14 @@ func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.Payme
 	status, ok := event.Data.Object["status"].(string)
 	if !ok {
 		// Handle error or log missing status
-		utils.Warn("Status not found in event data", map[string]interface{}{
+		utils.WarnContext(ctx, "Status not found in event data", map[string]interface{}{
 			"event": event.ID,
 		})
 		status = "completed" // Default status
 	}
 
 	// Handle checkout session completed
-	_, err := paymentClient.StorePayment(context.Background(), &proto.StorePaymentRequest{
+	_, err := paymentClient.StorePayment(ctx, &proto.StorePaymentRequest{
 		TransactionId: event.ID,
 		OrderId:       orderID,
 		CustomerId:    customerID,
@@ -186,7 +184,7 @@ func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.Payme
 	})
 
 	if err != nil {
-		utils.Error("Failed to store completed payment", map[string]interface{}{
+		utils.ErrorContext(ctx, "Failed to store completed payment", map[string]interface{}{
 			"error": err,
 			"event": event.ID,
 		})
@@ -194,9 +192,9 @@ func handleCheckoutSessionCompleted(event stripe.Event, paymentClient grpc.Payme
 	}
 }
 
-func handleCheckoutSessionExpired(event stripe.Event, paymentClient grpc.PaymentClient) {
+func handleCheckoutSessionExpired(ctx context.Context, event stripe.Event, paymentClient grpc.PaymentClient) {
 	// Handle checkout session expired
-	_, err := paymentClient.StorePayment(context.Background(), &proto.StorePaymentRequest{
+	_, err := paymentClient.StorePayment(ctx, &proto.StorePaymentRequest{
 		TransactionId: event.ID,
 		OrderId:       event.Data.Object["client_reference_id"].(string),
 		CustomerId:    event.Data.Object["customer"].(string),
@@ -205,14 +203,14 @@ func handleCheckoutSessionExpired(event stripe.Event, paymentClient grpc.Payment
 	})
 
 	if err != nil {
-		utils.Error("Failed to store expired payment", map[string]interface{}{
+		utils.ErrorContext(ctx, "Failed to store expired payment", map[string]interface{}{
 			"error": err,
 			"event": event.ID,
 		})
 		return
 	}
 
-	utils.Warn("Handling checkout session expired event", map[string]interface{}{
+	utils.WarnContext(ctx, "Handling checkout session expired event", map[string]interface{}{
 		"event": event.ID,
 	})
 }
@@ -235,29 +233,7 @@ func handleCheckoutSessionExpired(event stripe.Event, paymentClient grpc.Payment
 // @Router /api/v1/payments/{id} [get]
 func GetPayment(paymentClient grpc.PaymentClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		var customerID string
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
-			return
-		}
-
-		userId, ok := c.Get("user_id")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
-			return
-		}
-
-		customerID = userId.(string)
-		if userRole == "admin" {
-			customerID = "admin"
-		}
+		customerID := customerFilter(c)
 
 		paymentID := c.Param("id")
 
@@ -266,35 +242,28 @@ func GetPayment(paymentClient grpc.PaymentClient) gin.HandlerFunc {
 			CustomerId: customerID,
 		})
 		if err != nil {
-			utils.Error("Failed to get payment", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get payment", map[string]interface{}{
 				"error":      err,
 				"payment_id": paymentID,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get payment",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get payment")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get payment", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get payment", map[string]interface{}{
 				"error":      resp,
 				"payment_id": paymentID,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get payment",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get payment", nil)
 			return
 		}
 
@@ -320,29 +289,7 @@ func GetPayment(paymentClient grpc.PaymentClient) gin.HandlerFunc {
 // @Router /api/v1/payments/order/{id} [get]
 func GetPaymentByOrderID(paymentClient grpc.PaymentClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		var customerID string
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
-			return
-		}
-
-		userId, ok := c.Get("user_id")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
-			return
-		}
-
-		customerID = userId.(string)
-		if userRole == "admin" {
-			customerID = "admin"
-		}
+		customerID := customerFilter(c)
 
 		orderID := c.Param("id")
 
@@ -351,35 +298,28 @@ func GetPaymentByOrderID(paymentClient grpc.PaymentClient) gin.HandlerFunc {
 			CustomerId: customerID,
 		})
 		if err != nil {
-			utils.Error("Failed to get payment by order ID", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get payment by order ID", map[string]interface{}{
 				"error":    err,
 				"order_id": orderID,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get payment by order ID",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get payment by order ID")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get payment by order ID", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get payment by order ID", map[string]interface{}{
 				"error":    resp,
 				"order_id": orderID,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get payment by order ID",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get payment by order ID", nil)
 			return
 		}
 
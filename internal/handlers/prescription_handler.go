@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Order statuses of the prescription review. Orders awaiting review leave
+// that status only through ApprovePrescription or RejectPrescription.
+const (
+	OrderStatusAwaitingReview       = "awaiting_prescription_review"
+	OrderStatusPrescriptionApproved = "prescription_approved"
+	OrderStatusPrescriptionRejected = "prescription_rejected"
+)
+
+// isHeldForReview reports whether an order in status may be neither paid for
+// nor moved to another status outside of the review endpoints.
+func isHeldForReview(status string) bool {
+	return status == OrderStatusAwaitingReview || status == OrderStatusPrescriptionRejected
+}
+
+// isReviewDecision reports whether status records a pharmacist's decision,
+// which only the review endpoints may submit.
+func isReviewDecision(status string) bool {
+	return status == OrderStatusPrescriptionApproved || status == OrderStatusPrescriptionRejected
+}
+
+// ListPrescriptionReviews lists orders awaiting prescription review
+// @Summary List prescription review queue
+// @Description Lists orders awaiting prescription review, oldest first
+// @Tags Prescriptions
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} proto.ListAllOrdersResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/pharmacist/prescriptions [get]
+func ListPrescriptionReviews(orderClient grpc.OrderClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page := utils.GetIntQueryParam(c, "page", 1)
+		limit := utils.GetIntQueryParam(c, "limit", 0)
+
+		resp, err := orderClient.ListAllOrders(c.Request.Context(), &proto.ListAllOrdersRequest{
+			Filter: &proto.Filter{
+				Column:   "status",
+				Operator: "eq",
+				Value:    OrderStatusAwaitingReview,
+			},
+			SortBy:    "created_at",
+			SortOrder: "asc",
+			Page:      int32(page),
+			Limit:     int32(limit),
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to list prescription reviews", map[string]interface{}{
+				"error": err,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to list prescription reviews")
+			return
+		}
+
+		if !resp.Success {
+			utils.ErrorContext(c.Request.Context(), "Failed to list prescription reviews", map[string]interface{}{
+				"error": resp,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to list prescription reviews", nil)
+			return
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// GetPrescriptionReview returns an order with its prescription
+// @Summary Get prescription for review
+// @Description Returns an order awaiting review. The prescription file itself is served by /api/v1/orders/{id}/prescription. Every access is audited.
+// @Tags Prescriptions
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param id path string true "Order ID"
+// @Success 200 {object} proto.GetOrderResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/pharmacist/prescriptions/{id} [get]
+func GetPrescriptionReview(orderClient grpc.OrderClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := getOrder(c, orderClient, "admin")
+		if !ok {
+			return
+		}
+
+		if order.PrescriptionKey == nil && order.PrescriptionUrl == nil {
+			utils.RespondWithError(c, utils.ErrNotFound, "Order has no prescription", nil)
+			return
+		}
+
+		// Prescriptions are medical records; keep them out of shared caches.
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+type PrescriptionDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ApprovePrescription approves an order's prescription
+// @Summary Approve prescription
+// @Description Approves the prescription of an order awaiting review, releasing the order for payment
+// @Tags Prescriptions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param id path string true "Order ID"
+// @Param request body PrescriptionDecisionRequest false "Optional note"
+// @Success 200 {object} proto.UpdateOrderStatusResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/pharmacist/prescriptions/{id}/approve [post]
+func ApprovePrescription(orderClient grpc.OrderClient) gin.HandlerFunc {
+	return reviewPrescription(orderClient, OrderStatusPrescriptionApproved)
+}
+
+// RejectPrescription rejects an order's prescription
+// @Summary Reject prescription
+// @Description Rejects the prescription of an order awaiting review, closing the order. A reason is required.
+// @Tags Prescriptions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param id path string true "Order ID"
+// @Param request body PrescriptionDecisionRequest true "Reason for the rejection"
+// @Success 200 {object} proto.UpdateOrderStatusResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/pharmacist/prescriptions/{id}/reject [post]
+func RejectPrescription(orderClient grpc.OrderClient) gin.HandlerFunc {
+	return reviewPrescription(orderClient, OrderStatusPrescriptionRejected)
+}
+
+func reviewPrescription(orderClient grpc.OrderClient, decision string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PrescriptionDecisionRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
+				return
+			}
+		}
+		if decision == OrderStatusPrescriptionRejected && req.Reason == "" {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"reason": "A reason is required to reject a prescription"})
+			return
+		}
+
+		order, ok := getOrder(c, orderClient, "admin")
+		if !ok {
+			return
+		}
+		if order.Status != OrderStatusAwaitingReview {
+			utils.RespondWithError(c, utils.ErrConflict, "Order is not awaiting prescription review", map[string]string{"status": order.Status})
+			return
+		}
+
+		reviewerID := c.GetString("user_id")
+
+		// The order service refuses the decision if another reviewer decided
+		// since the order was read.
+		resp, err := orderClient.UpdateOrderStatus(c.Request.Context(), &proto.UpdateOrderStatusRequest{
+			OrderId:        order.OrderId,
+			CustomerId:     "admin",
+			Status:         decision,
+			Reason:         req.Reason,
+			ReviewerId:     reviewerID,
+			ExpectedStatus: OrderStatusAwaitingReview,
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to review prescription", map[string]interface{}{
+				"error":    err,
+				"order_id": order.OrderId,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to review prescription")
+			return
+		}
+
+		if !resp.Success {
+			utils.ErrorContext(c.Request.Context(), "Failed to review prescription", map[string]interface{}{
+				"error":    resp,
+				"order_id": order.OrderId,
+			})
+
+			if resp.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to review prescription", nil)
+			return
+		}
+
+		utils.InfoContext(c.Request.Context(), "Prescription reviewed", map[string]interface{}{
+			"order_id":    order.OrderId,
+			"decision":    decision,
+			"reviewer_id": reviewerID,
+		})
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// getOrder fetches the order in the path, as seen by customerID, responding
+// with the error when it cannot be loaded.
+func getOrder(c *gin.Context, orderClient grpc.OrderClient, customerID string) (*proto.GetOrderResponse, bool) {
+	orderID := c.Param("id")
+
+	resp, err := orderClient.GetOrder(c.Request.Context(), &proto.GetOrderRequest{
+		OrderId:    orderID,
+		CustomerId: customerID,
+	})
+	if err != nil {
+		utils.ErrorContext(c.Request.Context(), "Failed to get order", map[string]interface{}{
+			"error":    err,
+			"order_id": orderID,
+		})
+		utils.RespondWithGrpcError(c, err, "Failed to get order")
+		return nil, false
+	}
+
+	if !resp.Success {
+		utils.ErrorContext(c.Request.Context(), "Failed to get order", map[string]interface{}{
+			"error":    resp,
+			"order_id": orderID,
+		})
+
+		if resp.Error != nil {
+			errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
+			utils.WriteError(c, statusCode, errorResp)
+			return nil, false
+		}
+
+		// Fallback if error structure is not available
+		utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get order", nil)
+		return nil, false
+	}
+
+	return resp, true
+}
+
+// GetOrderPrescription gives access to an order's prescription file
+// @Summary Get order prescription
+// @Description Returns a short-lived presigned URL for the order's prescription, or with stream=true streams the file through the gateway. Customers can only reach their own orders. Every access is audited.
+// @Tags Prescriptions
+// @Produce json
+// @Produce application/octet-stream
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param id path string true "Order ID"
+// @Param stream query bool false "Stream the file instead of returning a URL"
+// @Success 200 {object} PrescriptionURLResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/orders/{id}/prescription [get]
+func GetOrderPrescription(cfg *config.Config, stores *storage.Stores, orderClient grpc.OrderClient) gin.HandlerFunc {
+	prescriptions := stores.Prescriptions
+
+	return func(c *gin.Context) {
+		orderID := c.Param("id")
+
+		// The order service only returns the order to its owner, or to
+		// anyone for the "admin" filter granted by prescriptions:read:any.
+		order, err := orderClient.GetOrder(c.Request.Context(), &proto.GetOrderRequest{
+			OrderId:    orderID,
+			CustomerId: customerFilter(c),
+		})
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to get order", map[string]interface{}{
+				"error":    err,
+				"order_id": orderID,
+			})
+			utils.RespondWithGrpcError(c, err, "Failed to get order")
+			return
+		}
+
+		if !order.Success {
+			utils.WarnContext(c.Request.Context(), "Failed to get order", map[string]interface{}{
+				"error":    order,
+				"order_id": orderID,
+			})
+
+			if order.Error != nil {
+				errorResp, statusCode := utils.ConvertProtoErrorToResponse(order.Error)
+				utils.WriteError(c, statusCode, errorResp)
+				return
+			}
+
+			// Fallback if error structure is not available
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get order", nil)
+			return
+		}
+
+		key, ok := prescriptionKey(cfg, stores, order)
+		if !ok {
+			utils.RespondWithError(c, utils.ErrNotFound, "Order has no prescription", nil)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+
+		if c.Query("stream") == "true" {
+			object, err := prescriptions.Get(c.Request.Context(), key)
+			if errors.Is(err, storage.ErrNotFound) {
+				utils.WarnContext(c.Request.Context(), "Prescription file is missing", map[string]interface{}{
+					"order_id": orderID,
+				})
+				utils.RespondWithError(c, utils.ErrNotFound, "Prescription file not found", nil)
+				return
+			}
+			if err != nil {
+				utils.ErrorContext(c.Request.Context(), "Failed to read prescription", map[string]interface{}{
+					"error":    err,
+					"order_id": orderID,
+				})
+				utils.RespondWithError(c, utils.ErrInternal, "Failed to read prescription", nil)
+				return
+			}
+			defer object.Body.Close()
+
+			c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, map[string]string{
+				"Content-Disposition": "inline",
+			})
+			return
+		}
+
+		expiresAt := time.Now().Add(cfg.Prescriptions.URLTTL)
+		url, err := prescriptions.Presign(c.Request.Context(), key, cfg.Prescriptions.URLTTL)
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to presign prescription URL", map[string]interface{}{
+				"error":    err,
+				"order_id": orderID,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to create prescription URL", nil)
+			return
+		}
+
+		c.JSON(http.StatusOK, PrescriptionURLResponse{
+			URL:       url,
+			ExpiresAt: expiresAt.Unix(),
+		})
+	}
+}
+
+// PrescriptionURLResponse is a temporary link to a prescription file.
+// @Description Presigned prescription URL
+type PrescriptionURLResponse struct {
+	URL       string `json:"url"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// prescriptionKey returns the storage key of the order's prescription. Older
+// orders only carry a public URL into the images bucket; with
+// PRESCRIPTION_LEGACY_URLS their files are looked up under the same key in
+// the prescriptions bucket, where they must have been moved.
+func prescriptionKey(cfg *config.Config, stores *storage.Stores, order *proto.GetOrderResponse) (string, bool) {
+	if key := order.GetPrescriptionKey(); key != "" {
+		return key, true
+	}
+	if order.PrescriptionUrl == nil || !cfg.Prescriptions.LegacyURLs {
+		return "", false
+	}
+	key, ok := storage.KeyFromURL(stores.Images, *order.PrescriptionUrl)
+	return key, ok && strings.HasPrefix(key, "prescriptions/")
+}
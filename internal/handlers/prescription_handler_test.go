@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeOrderClient serves a single order and, like the order service, applies
+// a status update only when its expected status still matches.
+type fakeOrderClient struct {
+	grpc.OrderClient
+
+	status   string
+	updates  []*proto.UpdateOrderStatusRequest
+	payments int
+}
+
+func (f *fakeOrderClient) GetOrder(ctx context.Context, req *proto.GetOrderRequest) (*proto.GetOrderResponse, error) {
+	return &proto.GetOrderResponse{Success: true, OrderId: req.OrderId, Status: f.status}, nil
+}
+
+func (f *fakeOrderClient) UpdateOrderStatus(ctx context.Context, req *proto.UpdateOrderStatusRequest) (*proto.UpdateOrderStatusResponse, error) {
+	f.updates = append(f.updates, req)
+	if req.ExpectedStatus != "" && req.ExpectedStatus != f.status {
+		return &proto.UpdateOrderStatusResponse{
+			Error: &proto.Error{Type: utils.ErrConflict.Code, Message: "Order status has changed"},
+		}, nil
+	}
+	f.status = req.Status
+	return &proto.UpdateOrderStatusResponse{Success: true}, nil
+}
+
+func (f *fakeOrderClient) GenerateNewPaymentUrl(ctx context.Context, req *proto.GenerateNewPaymentUrlRequest) (*proto.GenerateNewPaymentUrlResponse, error) {
+	f.payments++
+	return &proto.GenerateNewPaymentUrlResponse{Success: true, PaymentUrl: "https://pay.example/" + req.OrderId}, nil
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	utils.InitLogger(config.LoggingConfig{Level: "fatal"})
+}
+
+// serveOrder calls handler for order "o1" as a user with the given scope.
+func serveOrder(handler gin.HandlerFunc, scope, body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Handle(http.MethodPost, "/orders/:id", func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Set(rbac.ScopeKey, scope)
+		c.Next()
+	}, handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/o1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateOrderStatusHeldForReview(t *testing.T) {
+	for _, status := range []string{OrderStatusAwaitingReview, OrderStatusPrescriptionRejected} {
+		for _, scope := range []string{rbac.ScopeOwn, rbac.ScopeAny} {
+			orders := &fakeOrderClient{status: status}
+
+			rec := serveOrder(UpdateOrderStatus(orders), scope, `{"status": "paid"}`)
+			if rec.Code != http.StatusConflict {
+				t.Errorf("%s order as %s: got status %d, want 409", status, scope, rec.Code)
+			}
+			if len(orders.updates) != 0 {
+				t.Errorf("%s order as %s: status was updated", status, scope)
+			}
+		}
+	}
+}
+
+func TestUpdateOrderStatusRejectsDecisions(t *testing.T) {
+	for _, decision := range []string{OrderStatusPrescriptionApproved, OrderStatusPrescriptionRejected} {
+		orders := &fakeOrderClient{status: OrderStatusAwaitingReview}
+
+		rec := serveOrder(UpdateOrderStatus(orders), rbac.ScopeAny, `{"status": "`+decision+`"}`)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("setting %s: got status %d, want 403", decision, rec.Code)
+		}
+		if orders.status != OrderStatusAwaitingReview {
+			t.Errorf("setting %s: order moved to %s", decision, orders.status)
+		}
+	}
+}
+
+func TestUpdateOrderStatusPassesExpectedStatus(t *testing.T) {
+	orders := &fakeOrderClient{status: OrderStatusPrescriptionApproved}
+
+	rec := serveOrder(UpdateOrderStatus(orders), rbac.ScopeAny, `{"status": "cancelled"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if len(orders.updates) != 1 || orders.updates[0].ExpectedStatus != OrderStatusPrescriptionApproved {
+		t.Errorf("got updates %v, want one expecting %s", orders.updates, OrderStatusPrescriptionApproved)
+	}
+}
+
+func TestGenerateNewPaymentUrlRequiresApproval(t *testing.T) {
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{OrderStatusAwaitingReview, http.StatusConflict},
+		{OrderStatusPrescriptionRejected, http.StatusConflict},
+		{OrderStatusPrescriptionApproved, http.StatusOK},
+		{"pending", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		orders := &fakeOrderClient{status: tt.status}
+
+		rec := serveOrder(GenerateNewPaymentUrl(orders), rbac.ScopeOwn, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s order: got status %d, want %d", tt.status, rec.Code, tt.want)
+		}
+		if paid := orders.payments > 0; paid != (tt.want == http.StatusOK) {
+			t.Errorf("%s order: payment URL generated = %t", tt.status, paid)
+		}
+	}
+}
+
+func TestReviewPrescription(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(grpc.OrderClient) gin.HandlerFunc
+		body    string
+		status  string
+		want    int
+		final   string
+	}{
+		{"approve", ApprovePrescription, "", OrderStatusAwaitingReview, http.StatusOK, OrderStatusPrescriptionApproved},
+		{"reject", RejectPrescription, `{"reason": "Illegible"}`, OrderStatusAwaitingReview, http.StatusOK, OrderStatusPrescriptionRejected},
+		{"reject without reason", RejectPrescription, `{}`, OrderStatusAwaitingReview, http.StatusBadRequest, OrderStatusAwaitingReview},
+		{"approve rejected", ApprovePrescription, "", OrderStatusPrescriptionRejected, http.StatusConflict, OrderStatusPrescriptionRejected},
+		{"reject approved", RejectPrescription, `{"reason": "Expired"}`, OrderStatusPrescriptionApproved, http.StatusConflict, OrderStatusPrescriptionApproved},
+		{"approve paid", ApprovePrescription, "", "paid", http.StatusConflict, "paid"},
+	}
+
+	for _, tt := range tests {
+		orders := &fakeOrderClient{status: tt.status}
+
+		rec := serveOrder(tt.handler(orders), rbac.ScopeAny, tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+		if orders.status != tt.final {
+			t.Errorf("%s: order is %s, want %s", tt.name, orders.status, tt.final)
+		}
+		for _, update := range orders.updates {
+			if update.ExpectedStatus != OrderStatusAwaitingReview {
+				t.Errorf("%s: update expected status %q, want %q", tt.name, update.ExpectedStatus, OrderStatusAwaitingReview)
+			}
+		}
+	}
+}
+
+// fakeRacingOrderClient lets another reviewer decide between the read and
+// the update.
+type fakeRacingOrderClient struct {
+	*fakeOrderClient
+	decidedBy string
+}
+
+func (f *fakeRacingOrderClient) GetOrder(ctx context.Context, req *proto.GetOrderRequest) (*proto.GetOrderResponse, error) {
+	resp, err := f.fakeOrderClient.GetOrder(ctx, req)
+	f.status = f.decidedBy
+	return resp, err
+}
+
+func TestReviewPrescriptionStaleDecision(t *testing.T) {
+	orders := &fakeRacingOrderClient{
+		fakeOrderClient: &fakeOrderClient{status: OrderStatusAwaitingReview},
+		decidedBy:       OrderStatusPrescriptionRejected,
+	}
+
+	rec := serveOrder(ApprovePrescription(orders), rbac.ScopeAny, "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("got status %d, want 409", rec.Code)
+	}
+	if orders.status != OrderStatusPrescriptionRejected {
+		t.Errorf("order is %s, want the earlier decision %s", orders.status, OrderStatusPrescriptionRejected)
+	}
+}
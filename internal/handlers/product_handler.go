@@ -1,13 +1,14 @@
 package handlers
 
 import (
-	"context"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
 
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/PharmaKart/gateway-svc/pkg/utils"
 
@@ -31,12 +32,14 @@ type ProductUpdate struct {
 
 type Product struct {
 	ProductRequest
-	Image *multipart.FileHeader `form:"image" swaggerignore:"true"`
+	Image       *multipart.FileHeader `form:"image" swaggerignore:"true"`
+	ImageUpload string                `json:"image_upload" form:"image_upload"`
 }
 
 type UpdateProductReq struct {
 	ProductUpdate
-	Image *multipart.FileHeader `form:"image" swaggerignore:"true"`
+	Image       *multipart.FileHeader `form:"image" swaggerignore:"true"`
+	ImageUpload string                `json:"image_upload" form:"image_upload"`
 }
 
 // CreateProduct adds a new product to the inventory
@@ -52,7 +55,8 @@ type UpdateProductReq struct {
 // @Param price formData number true "Product Price" example:"9.99"
 // @Param stock formData integer true "Stock Quantity" example:"100"
 // @Param requires_prescription formData boolean false "Requires Prescription" example:"true"
-// @Param image formData file false "Product Image"
+// @Param image formData file false "Product image (JPEG or PNG)"
+// @Param image_upload formData string false "Upload token of a confirmed direct upload, instead of image"
 // @Success 200 {object} proto.CreateProductResponse
 // @Failure 400 {object} utils.ErrorResponse "Bad Request"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
@@ -60,57 +64,62 @@ type UpdateProductReq struct {
 // @Failure 409 {object} utils.ErrorResponse "Conflict"
 // @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
 // @Router /api/v1/admin/products [post]
-func CreateProduct(cfg *config.Config, productClient grpc.ProductClient) gin.HandlerFunc {
+func CreateProduct(cfg *config.Config, images storage.BlobStore, guard *scan.Guard, receipts *upload.Receipts, productClient grpc.ProductClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		policy := upload.ProductImagePolicy(cfg.Upload)
+		if !limitUploadRequest(c, "image", policy) {
+			return
+		}
+
 		var req Product
 		if err := c.ShouldBind(&req); err != nil {
-			utils.Error("Failed to bind request", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to bind request", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
+			return
+		}
+
+		if req.Image != nil && req.ImageUpload != "" {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"image_upload": "Send either an image or an upload token, not both"})
 			return
 		}
 
 		var imageURL string
+		var claim *uploadClaim
+
+		if req.ImageUpload != "" {
+			var ok bool
+			claim, ok = redeemUpload(c, ProductImageUploads(cfg, images), receipts, "image_upload", req.ImageUpload)
+			if !ok {
+				return
+			}
+			imageURL = images.URL(claim.Key)
+		}
 
 		if req.Image != nil {
-			// Validate file type
-			allowedExtensions := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".pdf": true}
-			ext := filepath.Ext(req.Image.Filename)
-			if !allowedExtensions[ext] {
-				utils.Error("Invalid file format", map[string]interface{}{
-					"extension": ext,
-				})
-				c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-					Type:    "VALIDATION_ERROR",
-					Message: "Invalid file format",
-					Details: map[string]string{"format": "Only JPG, JPEG, and PNG files are allowed"},
-				})
+			image, ok := validateUpload(c, "image", req.Image, policy)
+			if !ok {
+				return
+			}
+			if !scanUpload(c, guard, "image", UploadPurposeProducts, image) {
 				return
 			}
 
-			// Upload image to S3
-			imageURLResp, err := utils.UploadImageToS3(c, cfg, "products", req.Image)
+			// Upload image to the blob store
+			key, err := storage.Upload(c.Request.Context(), images, "products", image, storage.PutOptions{})
 			if err != nil {
-				utils.Error("Failed to upload image to S3", map[string]interface{}{
+				utils.ErrorContext(c.Request.Context(), "Failed to upload image", map[string]interface{}{
 					"error": err,
 				})
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-					Type:    "INTERNAL_ERROR",
-					Message: "Failed to upload image",
-					Details: map[string]string{"error": err.Error()},
-				})
+				utils.RespondWithError(c, utils.ErrInternal, "Failed to upload image", map[string]string{"error": err.Error()})
 				return
 			}
-			imageURL = imageURLResp
+			imageURL = images.URL(key)
 		}
 
 		// Call the gRPC service to create product
-		resp, err := productClient.CreateProduct(context.Background(), &proto.CreateProductRequest{
+		resp, err := productClient.CreateProduct(c.Request.Context(), &proto.CreateProductRequest{
 			Product: &proto.Product{
 				Name:                 req.Name,
 				Description:          req.Description,
@@ -120,35 +129,29 @@ func CreateProduct(cfg *config.Config, productClient grpc.ProductClient) gin.Han
 				ImageUrl:             imageURL,
 			},
 		})
+		claim.settle(c.Request.Context(), err, resp.GetSuccess())
 
 		if err != nil {
-			utils.Error("Failed to create product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to create product", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to create product",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to create product")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to create product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to create product", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to create product",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to create product", nil)
 			return
 		}
 
@@ -172,39 +175,32 @@ func GetProduct(productClient grpc.ProductClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		productID := c.Param("id")
 
-		resp, err := productClient.GetProduct(context.Background(), &proto.GetProductRequest{
+		resp, err := productClient.GetProduct(c.Request.Context(), &proto.GetProductRequest{
 			ProductId: productID,
 		})
 		if err != nil {
-			utils.Error("Failed to get product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get product", map[string]interface{}{
 				"error":      err,
 				"product_id": productID,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get product",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get product")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get product", map[string]interface{}{
 				"error":      resp,
 				"product_id": productID,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get product",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get product", nil)
 			return
 		}
 
@@ -250,7 +246,7 @@ func GetProducts(productClient grpc.ProductClient) gin.HandlerFunc {
 			}
 		}
 
-		resp, err := productClient.ListProducts(context.Background(), &proto.ListProductsRequest{
+		resp, err := productClient.ListProducts(c.Request.Context(), &proto.ListProductsRequest{
 			Search:    search,
 			Filter:    filter,
 			SortBy:    sortBy,
@@ -260,35 +256,28 @@ func GetProducts(productClient grpc.ProductClient) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			utils.Error("Failed to get products", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get products", map[string]interface{}{
 				"error": err,
 				"page":  page,
 				"limit": limit,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get products",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get products")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get products", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get products", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get products",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get products", nil)
 			return
 		}
 
@@ -313,58 +302,63 @@ func GetProducts(productClient grpc.ProductClient) gin.HandlerFunc {
 // @Failure 404 {object} utils.ErrorResponse "Not Found"
 // @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
 // @Router /api/v1/admin/products/{id} [put]
-func UpdateProduct(cfg *config.Config, productClient grpc.ProductClient) gin.HandlerFunc {
+func UpdateProduct(cfg *config.Config, images storage.BlobStore, guard *scan.Guard, receipts *upload.Receipts, productClient grpc.ProductClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		productID := c.Param("id")
 
+		policy := upload.ProductImagePolicy(cfg.Upload)
+		if !limitUploadRequest(c, "image", policy) {
+			return
+		}
+
 		var req UpdateProductReq
 		if err := c.ShouldBind(&req); err != nil {
-			utils.Error("Failed to bind request", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to bind request", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
+			return
+		}
+
+		if req.Image != nil && req.ImageUpload != "" {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"image_upload": "Send either an image or an upload token, not both"})
 			return
 		}
 
 		var imageURL string
+		var claim *uploadClaim
 
-		if req.Image.Filename != "" {
-			// Validate file type
-			allowedExtensions := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".pdf": true}
-			ext := filepath.Ext(req.Image.Filename)
-			if !allowedExtensions[ext] {
-				utils.Error("Invalid file format", map[string]interface{}{
-					"extension": ext,
-				})
-				c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-					Type:    "VALIDATION_ERROR",
-					Message: "Invalid file format",
-					Details: map[string]string{"format": "Only JPG, JPEG, and PNG files are allowed"},
-				})
+		if req.ImageUpload != "" {
+			var ok bool
+			claim, ok = redeemUpload(c, ProductImageUploads(cfg, images), receipts, "image_upload", req.ImageUpload)
+			if !ok {
+				return
+			}
+			imageURL = images.URL(claim.Key)
+		}
+
+		if req.Image != nil {
+			image, ok := validateUpload(c, "image", req.Image, policy)
+			if !ok {
+				return
+			}
+			if !scanUpload(c, guard, "image", UploadPurposeProducts, image) {
 				return
 			}
 
-			// Upload image to S3
-			imageURLResp, err := utils.UploadImageToS3(c, cfg, "products", req.Image)
+			// Upload image to the blob store
+			key, err := storage.Upload(c.Request.Context(), images, "products", image, storage.PutOptions{})
 			if err != nil {
-				utils.Error("Failed to upload image to S3", map[string]interface{}{
+				utils.ErrorContext(c.Request.Context(), "Failed to upload image", map[string]interface{}{
 					"error": err,
 				})
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-					Type:    "INTERNAL_ERROR",
-					Message: "Failed to upload image",
-					Details: map[string]string{"error": err.Error()},
-				})
+				utils.RespondWithError(c, utils.ErrInternal, "Failed to upload image", map[string]string{"error": err.Error()})
 				return
 			}
-			imageURL = imageURLResp
+			imageURL = images.URL(key)
 		}
 
-		resp, err := productClient.UpdateProduct(context.Background(), &proto.UpdateProductRequest{
+		resp, err := productClient.UpdateProduct(c.Request.Context(), &proto.UpdateProductRequest{
 			ProductId: productID,
 			Product: &proto.Product{
 				Name:                 req.Name,
@@ -374,36 +368,30 @@ func UpdateProduct(cfg *config.Config, productClient grpc.ProductClient) gin.Han
 				ImageUrl:             imageURL,
 			},
 		})
+		claim.settle(c.Request.Context(), err, resp.GetSuccess())
 		if err != nil {
-			utils.Error("Failed to update product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update product", map[string]interface{}{
 				"error":      err,
 				"product_id": productID,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to update product",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to update product")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to update product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update product", map[string]interface{}{
 				"error":      resp.Message,
 				"product_id": productID,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -431,39 +419,32 @@ func DeleteProduct(productClient grpc.ProductClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		productID := c.Param("id")
 
-		resp, err := productClient.DeleteProduct(context.Background(), &proto.DeleteProductRequest{
+		resp, err := productClient.DeleteProduct(c.Request.Context(), &proto.DeleteProductRequest{
 			ProductId: productID,
 		})
 		if err != nil {
-			utils.Error("Failed to delete product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to delete product", map[string]interface{}{
 				"error":      err,
 				"product_id": productID,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to delete product",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to delete product")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to delete product", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to delete product", map[string]interface{}{
 				"error":      resp.Message,
 				"product_id": productID,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -499,51 +480,40 @@ func UpdateStock(productClient grpc.ProductClient) gin.HandlerFunc {
 
 		var req proto.UpdateStockRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			utils.Error("Failed to bind request", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to bind request", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
 		req.ProductId = productID
 
-		resp, err := productClient.UpdateStock(context.Background(), &req)
+		resp, err := productClient.UpdateStock(c.Request.Context(), &req)
 		if err != nil {
-			utils.Error("Failed to update stock", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update stock", map[string]interface{}{
 				"error":           err,
 				"product_id":      productID,
 				"quantity_change": req.QuantityChange,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to update stock",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to update stock")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to update stock", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update stock", map[string]interface{}{
 				"error":      resp.Message,
 				"product_id": productID,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -595,7 +565,7 @@ func GetInventoryLogs(productClient grpc.ProductClient) gin.HandlerFunc {
 			}
 		}
 
-		resp, err := productClient.GetInventoryLogs(context.Background(), &proto.GetInventoryLogsRequest{
+		resp, err := productClient.GetInventoryLogs(c.Request.Context(), &proto.GetInventoryLogsRequest{
 			ProductId: productID,
 			Filter:    filter,
 			SortBy:    sortBy,
@@ -605,33 +575,26 @@ func GetInventoryLogs(productClient grpc.ProductClient) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			utils.Error("Failed to get inventory logs", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get inventory logs", map[string]interface{}{
 				"error":      err,
 				"product_id": productID,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get inventory logs",
-				Details: map[string]string{"error": err.Error()},
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get inventory logs")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get inventory logs", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get inventory logs", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get inventory logs",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get inventory logs", nil)
 			return
 		}
 
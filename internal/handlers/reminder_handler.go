@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
@@ -35,42 +34,32 @@ func ScheduleReminder(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req proto.ScheduleReminderRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
-		resp, err := reminderClient.ScheduleReminder(context.Background(), &req)
+		resp, err := reminderClient.ScheduleReminder(c.Request.Context(), &req)
 		if err != nil {
-			utils.Error("Failed to schedule reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to schedule reminder", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to schedule reminder",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to schedule reminder")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to schedule reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to schedule reminder", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to schedule reminder",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to schedule reminder", nil)
 			return
 		}
 
@@ -117,7 +106,7 @@ func ListReminders(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 			}
 		}
 
-		resp, err := reminderClient.ListReminders(context.Background(), &proto.ListRemindersRequest{
+		resp, err := reminderClient.ListReminders(c.Request.Context(), &proto.ListRemindersRequest{
 			Filter:    filter,
 			SortBy:    sortBy,
 			SortOrder: sortOrder,
@@ -125,31 +114,25 @@ func ListReminders(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 			Limit:     int32(limit),
 		})
 		if err != nil {
-			utils.Error("Failed to get reminders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get reminders", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get reminders",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get reminders")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get reminders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get reminders", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get reminders",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get reminders", nil)
 			return
 		}
 
@@ -181,10 +164,7 @@ func ListCustomerReminders(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
@@ -206,7 +186,7 @@ func ListCustomerReminders(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 			}
 		}
 
-		resp, err := reminderClient.ListCustomerReminders(context.Background(), &proto.ListCustomerRemindersRequest{
+		resp, err := reminderClient.ListCustomerReminders(c.Request.Context(), &proto.ListCustomerRemindersRequest{
 			CustomerId: customerID.(string),
 			Filter:     filter,
 			SortBy:     sortBy,
@@ -215,31 +195,25 @@ func ListCustomerReminders(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 			Limit:      int32(limit),
 		})
 		if err != nil {
-			utils.Error("Failed to get reminders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get reminders", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get reminders",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get reminders")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get customer reminders", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get customer reminders", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get reminders",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get reminders", nil)
 			return
 		}
 
@@ -264,45 +238,36 @@ func DeleteReminder(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
 		reminderID := c.Param("reminder_id")
 
-		resp, err := reminderClient.DeleteReminder(context.Background(), &proto.DeleteReminderRequest{
+		resp, err := reminderClient.DeleteReminder(c.Request.Context(), &proto.DeleteReminderRequest{
 			CustomerId: customerID.(string),
 			ReminderId: reminderID,
 		})
 		if err != nil {
-			utils.Error("Failed to delete reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to delete reminder", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to delete reminder",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to delete reminder")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to delete reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to delete reminder", map[string]interface{}{
 				"error": resp.Message,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -329,10 +294,7 @@ func UpdateReminder(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
@@ -340,44 +302,34 @@ func UpdateReminder(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 
 		var req proto.UpdateReminderRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "VALIDATION_ERROR",
-				Message: "Invalid request format",
-				Details: map[string]string{"format": err.Error()},
-			})
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
 			return
 		}
 
 		req.CustomerId = customerID.(string)
 		req.ReminderId = reminderID
 
-		resp, err := reminderClient.UpdateReminder(context.Background(), &req)
+		resp, err := reminderClient.UpdateReminder(c.Request.Context(), &req)
 		if err != nil {
-			utils.Error("Failed to update reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update reminder", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to update reminder",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to update reminder")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to update reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to update reminder", map[string]interface{}{
 				"error": resp.Message,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -402,45 +354,36 @@ func ToggleReminder(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		customerID, ok := c.Get("user_id")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "User ID not found in token", nil)
 			return
 		}
 
 		reminderID := c.Param("reminder_id")
 
-		resp, err := reminderClient.ToggleReminder(context.Background(), &proto.ToggleReminderRequest{
+		resp, err := reminderClient.ToggleReminder(c.Request.Context(), &proto.ToggleReminderRequest{
 			CustomerId: customerID.(string),
 			ReminderId: reminderID,
 		})
 		if err != nil {
-			utils.Error("Failed to toggle reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to toggle reminder", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to toggle reminder",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to toggle reminder")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to toggle reminder", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to toggle reminder", map[string]interface{}{
 				"error": resp.Message,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			return
 		}
 
@@ -470,27 +413,7 @@ func ToggleReminder(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 // @Router /api/v1/reminders/{reminder_id}/logs [get]
 func ListReminderLogs(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get("user_role")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User Role not found in token",
-			})
-			return
-		}
-
-		customerID, ok := c.Get("user_id")
-		if !ok {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "User ID not found in token",
-			})
-			return
-		}
-
-		if userRole == "admin" {
-			customerID = "admin"
-		}
+		customerID := customerFilter(c)
 
 		reminderID := c.Param("reminder_id")
 
@@ -512,8 +435,8 @@ func ListReminderLogs(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 			}
 		}
 
-		resp, err := reminderClient.ListReminderLogs(context.Background(), &proto.ListReminderLogsRequest{
-			CustomerId: customerID.(string),
+		resp, err := reminderClient.ListReminderLogs(c.Request.Context(), &proto.ListReminderLogsRequest{
+			CustomerId: customerID,
 			ReminderId: reminderID,
 			Filter:     filter,
 			SortBy:     sortBy,
@@ -522,31 +445,25 @@ func ListReminderLogs(reminderClient grpc.ReminderClient) gin.HandlerFunc {
 			Limit:      int32(limit),
 		})
 		if err != nil {
-			utils.Error("Failed to get reminder logs", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get reminder logs", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{
-				Type:    "INTERNAL_ERROR",
-				Message: "Failed to get reminder logs",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to get reminder logs")
 			return
 		}
 
 		if !resp.Success {
-			utils.Error("Failed to get reminder logs", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to get reminder logs", map[string]interface{}{
 				"error": resp,
 			})
 
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: "Failed to get reminder logs",
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, "Failed to get reminder logs", nil)
 			return
 		}
 
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Upload purposes. Each is also the folder confirmed uploads are stored in.
+const (
+	UploadPurposeProducts      = "products"
+	UploadPurposePrescriptions = "prescriptions"
+)
+
+// DirectUpload describes where files for one purpose are uploaded directly
+// to storage, and how they are checked and stored once confirmed.
+type DirectUpload struct {
+	purpose string
+	field   string
+	store   storage.BlobStore
+	policy  upload.Policy
+	options storage.PutOptions
+	ttl     time.Duration
+}
+
+// ProductImageUploads accepts product images, which are stored publicly.
+func ProductImageUploads(cfg *config.Config, images storage.BlobStore) *DirectUpload {
+	return &DirectUpload{
+		purpose: UploadPurposeProducts,
+		field:   "image",
+		store:   images,
+		policy:  upload.ProductImagePolicy(cfg.Upload),
+		ttl:     cfg.Upload.PresignTTL,
+	}
+}
+
+// PrescriptionUploads accepts prescriptions, which are stored privately.
+func PrescriptionUploads(cfg *config.Config, prescriptions storage.BlobStore) *DirectUpload {
+	return &DirectUpload{
+		purpose: UploadPurposePrescriptions,
+		field:   "prescription",
+		store:   prescriptions,
+		policy:  upload.PrescriptionPolicy(cfg.Upload),
+		options: storage.PutOptions{
+			Private:              true,
+			ServerSideEncryption: cfg.Prescriptions.ServerSideEncryption,
+			KMSKeyID:             cfg.Prescriptions.KMSKeyID,
+		},
+		ttl: cfg.Upload.PresignTTL,
+	}
+}
+
+// pendingPrefix is the folder the user's unconfirmed uploads are posted to.
+// Confirmed uploads are copied out of it, so the bucket can expire it.
+func (u *DirectUpload) pendingPrefix(userID string) string {
+	return fmt.Sprintf("uploads/%s/%s/", u.purpose, userID)
+}
+
+// confirmedFolder is where checked uploads are kept, privately, until their
+// receipt is redeemed. Like pending uploads, those never redeemed are left
+// for the bucket to expire.
+func (u *DirectUpload) confirmedFolder() string {
+	return "uploads/confirmed/" + u.purpose
+}
+
+// claim copies the confirmed upload at key into the purpose's folder, stored
+// like a form upload, and returns the copy's key. The confirmed upload is
+// kept until the claim is settled.
+func (u *DirectUpload) claim(ctx context.Context, key string) (string, error) {
+	info, err := u.store.Head(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	claimed := fmt.Sprintf("%s/%s%s", u.purpose, utils.NewRequestID(), upload.Extension(info.ContentType))
+	if err := u.store.Copy(ctx, key, claimed, u.options); err != nil {
+		return "", err
+	}
+	return claimed, nil
+}
+
+// uploadClaim is a confirmed upload copied out for a request.
+type uploadClaim struct {
+	Key string
+
+	target   *DirectUpload
+	receipts *upload.Receipts
+	token    string
+	staged   string
+}
+
+// settle finishes the claim once the backend has answered the request it was
+// made for. When the request succeeded the confirmed upload is deleted.
+// Otherwise the receipt is released so the upload can be submitted again,
+// and the copy is deleted if the backend refused the request; after a
+// transport error the backend may still have stored its key.
+func (u *uploadClaim) settle(ctx context.Context, err error, success bool) {
+	if u == nil {
+		return
+	}
+
+	stale := u.staged
+	if err != nil || !success {
+		u.receipts.Release(u.token)
+		if err != nil {
+			return
+		}
+		stale = u.Key
+	}
+
+	if err := u.target.store.Delete(ctx, stale); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		utils.WarnContext(ctx, "Failed to delete upload", map[string]interface{}{
+			"error": err,
+			"key":   stale,
+		})
+	}
+}
+
+// UploadURLRequest represents a request for a presigned upload.
+// @Description Presigned upload request
+type UploadURLRequest struct {
+	ContentType string `json:"content_type" binding:"required" example:"image/png"`
+}
+
+// UploadURLResponse is a presigned POST: the client sends a multipart form
+// to URL with every field, followed by the file in a field named "file".
+// @Description Presigned upload
+type UploadURLResponse struct {
+	Key       string            `json:"key" example:"uploads/products/123/abc.png"`
+	URL       string            `json:"url" example:"https://bucket.s3.us-east-1.amazonaws.com/"`
+	Method    string            `json:"method" example:"POST"`
+	Fields    map[string]string `json:"fields"`
+	MaxSize   int64             `json:"max_size" example:"5242880"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// ConfirmUploadRequest represents a request to confirm a direct upload.
+// @Description Upload confirmation request
+type ConfirmUploadRequest struct {
+	Key string `json:"key" binding:"required" example:"uploads/products/123/abc.png"`
+}
+
+// ConfirmUploadResponse carries the token to submit in place of the file.
+// @Description Confirmed upload
+type ConfirmUploadResponse struct {
+	UploadToken string    `json:"upload_token"`
+	ContentType string    `json:"content_type" example:"image/png"`
+	Size        int64     `json:"size" example:"48213"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// CreateUploadURL issues a presigned upload straight to storage
+// @Summary Request a direct upload
+// @Description Issues a presigned POST for uploading a file straight to storage, restricted to the given content type and the purpose's size limit. Once posted, the upload must be confirmed.
+// @Tags Uploads
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param purpose path string true "Upload purpose" Enums(products, prescriptions)
+// @Param request body UploadURLRequest true "Content type of the file"
+// @Success 200 {object} UploadURLResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/uploads/{purpose} [post]
+func CreateUploadURL(target *DirectUpload) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UploadURLRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
+			return
+		}
+
+		contentType, ok := target.policy.Allows(req.ContentType)
+		if !ok {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"content_type": "File type is not allowed"})
+			return
+		}
+
+		key := target.pendingPrefix(c.GetString("user_id")) + utils.NewRequestID() + upload.Extension(contentType)
+		post, err := target.store.PresignPost(c.Request.Context(), key, storage.PostConditions{
+			ContentType:          contentType,
+			MaxSize:              target.policy.MaxSize,
+			ServerSideEncryption: target.options.ServerSideEncryption,
+			KMSKeyID:             target.options.KMSKeyID,
+		}, target.ttl)
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to presign upload", map[string]interface{}{
+				"error":   err,
+				"purpose": target.purpose,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to presign upload", nil)
+			return
+		}
+
+		c.JSON(http.StatusOK, UploadURLResponse{
+			Key:       key,
+			URL:       post.URL,
+			Method:    http.MethodPost,
+			Fields:    post.Fields,
+			MaxSize:   target.policy.MaxSize,
+			ExpiresAt: post.ExpiresAt,
+		})
+	}
+}
+
+// ConfirmUpload checks a direct upload and issues a token for it
+// @Summary Confirm a direct upload
+// @Description Checks that a direct upload exists, validates and scans it like a form upload, and returns an upload token to submit in place of the file. Rejected uploads are deleted.
+// @Tags Uploads
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "Bearer token"
+// @Param purpose path string true "Upload purpose" Enums(products, prescriptions)
+// @Param request body ConfirmUploadRequest true "Key of the uploaded object"
+// @Success 200 {object} ConfirmUploadResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /api/v1/uploads/{purpose}/confirm [post]
+func ConfirmUpload(target *DirectUpload, guard *scan.Guard, receipts *upload.Receipts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ConfirmUploadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.RespondWithError(c, utils.ErrValidation, "Invalid request format", map[string]string{"format": err.Error()})
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if !strings.HasPrefix(req.Key, target.pendingPrefix(userID)) {
+			utils.RespondWithError(c, utils.ErrNotFound, "Upload not found", nil)
+			return
+		}
+
+		object, err := target.store.Get(c.Request.Context(), req.Key)
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.RespondWithError(c, utils.ErrNotFound, "Upload not found", nil)
+			return
+		}
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to fetch upload", map[string]interface{}{
+				"error": err,
+				"key":   req.Key,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to fetch upload", nil)
+			return
+		}
+
+		// The pending object is only a staging copy, whatever the outcome
+		defer func() {
+			if err := target.store.Delete(c.Request.Context(), req.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				utils.WarnContext(c.Request.Context(), "Failed to delete pending upload", map[string]interface{}{
+					"error": err,
+					"key":   req.Key,
+				})
+			}
+		}()
+
+		data, err := io.ReadAll(io.LimitReader(object.Body, target.policy.MaxSize+1))
+		object.Body.Close()
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to read upload", map[string]interface{}{
+				"error": err,
+				"key":   req.Key,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to read upload", nil)
+			return
+		}
+
+		file, err := upload.Check(target.field, req.Key, object.ContentType, data, target.policy)
+		if !checkedUpload(c, target.field, req.Key, err) {
+			return
+		}
+		if !scanUpload(c, guard, target.field, target.purpose, file) {
+			return
+		}
+
+		// Keep the sanitized file privately until the receipt is redeemed
+		options := target.options
+		options.Private = true
+		key, err := storage.Upload(c.Request.Context(), target.store, target.confirmedFolder(), file, options)
+		if err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to store upload", map[string]interface{}{
+				"error": err,
+				"key":   req.Key,
+			})
+			utils.RespondWithError(c, utils.ErrInternal, "Failed to store upload", nil)
+			return
+		}
+
+		token, expiresAt := receipts.Issue(key, target.purpose, userID)
+		c.JSON(http.StatusOK, ConfirmUploadResponse{
+			UploadToken: token,
+			ContentType: file.ContentType,
+			Size:        file.Size(),
+			ExpiresAt:   expiresAt,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// serveUpload calls handler as user "u1".
+func serveUpload(handler gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.POST("/upload", func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Next()
+	}, handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConfirmAndRedeemUpload(t *testing.T) {
+	cfg := &config.Config{
+		Storage: config.StorageConfig{LocalDir: t.TempDir(), SigningKey: "upload-test"},
+		Upload:  config.UploadConfig{MaxPrescriptionSize: 1 << 20},
+	}
+	store, err := storage.NewLocalStore(cfg.Storage, "prescriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := PrescriptionUploads(cfg, store)
+	receipts := upload.NewReceipts("upload-test", time.Minute)
+	ctx := context.Background()
+
+	pending := target.pendingPrefix("u1") + "scan.pdf"
+	pdf := "%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n"
+	if err := store.Put(ctx, pending, strings.NewReader(pdf), storage.PutOptions{ContentType: upload.TypePDF, Private: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serveUpload(ConfirmUpload(target, scan.NewGuard(scan.NoopScanner{}, store, false), receipts), `{"key": "`+pending+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm: got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var confirmed ConfirmUploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &confirmed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Head(ctx, pending); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("confirm: pending upload was kept: %v", err)
+	}
+	staged, err := receipts.Open(confirmed.UploadToken, UploadPurposePrescriptions, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The backend's answer to the request the upload was submitted with
+	var claimed []string
+	redeem := func(c *gin.Context) {
+		claim, ok := redeemUpload(c, target, receipts, "prescription_upload", c.Query("token"))
+		if !ok {
+			return
+		}
+		claimed = append(claimed, claim.Key)
+
+		switch c.Query("backend") {
+		case "refused":
+			claim.settle(ctx, nil, false)
+		case "unreachable":
+			claim.settle(ctx, errors.New("connection refused"), false)
+		default:
+			claim.settle(ctx, nil, true)
+		}
+		c.Status(http.StatusOK)
+	}
+	serveRedeem := func(backend string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/redeem", func(c *gin.Context) { c.Set("user_id", "u1") }, redeem)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/redeem?backend="+backend+"&token="+confirmed.UploadToken, nil))
+		return rec
+	}
+	exists := func(key string) bool {
+		_, err := store.Head(ctx, key)
+		return err == nil
+	}
+
+	// A refused request leaves the upload to be submitted again
+	if rec := serveRedeem("refused"); rec.Code != http.StatusOK {
+		t.Fatalf("refused: got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if len(claimed) != 1 || exists(claimed[0]) {
+		t.Errorf("refused: claimed %v, want the copy deleted", claimed)
+	}
+
+	// The backend may have stored the key before the connection failed
+	if rec := serveRedeem("unreachable"); rec.Code != http.StatusOK {
+		t.Fatalf("unreachable: got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if len(claimed) != 2 || !exists(claimed[1]) {
+		t.Errorf("unreachable: claimed %v, want the copy kept", claimed)
+	}
+
+	if rec := serveRedeem("ok"); rec.Code != http.StatusOK {
+		t.Fatalf("redeem: got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if len(claimed) != 3 || !strings.HasPrefix(claimed[2], UploadPurposePrescriptions+"/") {
+		t.Fatalf("redeem: claimed %v, want a key in the prescriptions folder", claimed)
+	}
+	info, err := store.Head(ctx, claimed[2])
+	if err != nil || info.ContentType != upload.TypePDF {
+		t.Errorf("redeem: claimed object %+v, %v", info, err)
+	}
+	if exists(staged) {
+		t.Errorf("redeem: confirmed upload %s was kept", staged)
+	}
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, store.Prefix()+claimed[2], nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("redeem: claimed object is readable without a signature: status %d", rec.Code)
+	}
+
+	if rec := serveRedeem("ok"); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused token: got status %d, want 400", rec.Code)
+	}
+	if len(claimed) != 3 {
+		t.Errorf("reused token: claimed %v", claimed)
+	}
+}
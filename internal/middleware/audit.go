@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/audit"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxAuditedBodySize bounds the JSON body buffered for the change summary.
+	maxAuditedBodySize = 64 * 1024
+	// maxAuditedValueLength truncates long values in the change summary.
+	maxAuditedValueLength = 200
+)
+
+// AuditMiddleware records every mutating request (POST, PUT, PATCH and
+// DELETE) passing through the group it is installed on.
+func AuditMiddleware(sink audit.Sink) gin.HandlerFunc {
+	return auditRequests(sink, func(c *gin.Context) bool {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			return true
+		}
+		return false
+	})
+}
+
+// AccessAuditMiddleware records every request, reads included, for routes
+// that expose sensitive records such as prescriptions.
+func AccessAuditMiddleware(sink audit.Sink) gin.HandlerFunc {
+	return auditRequests(sink, func(c *gin.Context) bool {
+		return true
+	})
+}
+
+// PrescriptionAuditMiddleware records requests that upload a prescription,
+// or submit one uploaded directly to storage.
+func PrescriptionAuditMiddleware(sink audit.Sink) gin.HandlerFunc {
+	return auditRequests(sink, func(c *gin.Context) bool {
+		form := c.Request.MultipartForm
+		return form != nil && (len(form.File["prescription"]) > 0 || len(form.Value["prescription_upload"]) > 0)
+	})
+}
+
+// auditRequests writes an audit event once the request has been handled and
+// shouldRecord, which may inspect the parsed form, accepts it. A failure to
+// write the event is logged; the request itself has already been served.
+func auditRequests(sink audit.Sink, shouldRecord func(*gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := bufferJSONBody(c)
+
+		c.Next()
+
+		if !shouldRecord(c) {
+			return
+		}
+
+		event := &audit.Event{
+			Timestamp:  time.Now().UTC(),
+			ActorID:    c.GetString("user_id"),
+			ActorRole:  c.GetString("user_role"),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			ResourceID: c.Param("id"),
+			Changes:    changeSummary(c, body),
+			Status:     c.Writer.Status(),
+			Result:     audit.ResultSuccess,
+			RequestID:  c.GetString(utils.RequestIDKey),
+		}
+		if event.Status >= http.StatusBadRequest {
+			event.Result = audit.ResultFailure
+		}
+
+		// Record the event even if the client has gone away in the meantime
+		if err := sink.Write(context.WithoutCancel(c.Request.Context()), event); err != nil {
+			utils.ErrorContext(c.Request.Context(), "Failed to write audit event", map[string]interface{}{
+				"error": err,
+				"route": event.Route,
+			})
+		}
+	}
+}
+
+// bufferJSONBody reads a small JSON body so it can be summarised after the
+// handler has consumed it, and puts it back for the handler.
+func bufferJSONBody(c *gin.Context) []byte {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return nil
+	}
+	if c.Request.ContentLength < 0 || c.Request.ContentLength > maxAuditedBodySize {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditedBodySize))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// changeSummary lists the fields the request submitted: top-level JSON keys,
+// form values, and the name and size of uploaded files. Fields redacted from
+// logs are masked here too.
+func changeSummary(c *gin.Context, body []byte) map[string]string {
+	changes := make(map[string]string)
+	record := func(key, value string) {
+		if utils.IsRedactedField(key) {
+			value = utils.RedactedValue
+		}
+		changes[key] = truncate(value)
+	}
+
+	var fields map[string]interface{}
+	if len(body) > 0 && json.Unmarshal(body, &fields) == nil {
+		for key, value := range fields {
+			record(key, summarizeValue(value))
+		}
+	}
+
+	if form := c.Request.MultipartForm; form != nil {
+		for key, values := range form.Value {
+			record(key, strings.Join(values, ","))
+		}
+		for key, files := range form.File {
+			if len(files) > 0 {
+				record(key, fmt.Sprintf("file %s (%d bytes)", files[0].Filename, files[0].Size))
+			}
+		}
+	} else if c.Request.PostForm != nil {
+		for key, values := range c.Request.PostForm {
+			record(key, strings.Join(values, ","))
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// summarizeValue prints a JSON value, masking redacted fields nested in it.
+func summarizeValue(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}, []interface{}:
+		redacted, err := json.Marshal(utils.Redact(v))
+		if err != nil {
+			return utils.RedactedValue
+		}
+		return string(redacted)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func truncate(value string) string {
+	if len(value) <= maxAuditedValueLength {
+		return value
+	}
+	return value[:maxAuditedValueLength] + "..."
+}
@@ -1,16 +1,20 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/proto"
 	"github.com/PharmaKart/gateway-svc/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AuthMiddleware(authClient grpc.AuthClient) gin.HandlerFunc {
+// AuthMiddleware verifies the bearer token and rejects tokens found on the
+// revocation list.
+func AuthMiddleware(authClient grpc.AuthClient, revocations *grpc.RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		if c.Request.URL.Path == "/api/v1/payment/webhook" {
@@ -20,26 +24,20 @@ func AuthMiddleware(authClient grpc.AuthClient) gin.HandlerFunc {
 
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			utils.Error("Authorization header is missing", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Authorization header is missing", map[string]interface{}{
 				"path": c.Request.URL.Path,
 			})
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "Authorization header is missing",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "Authorization header is missing", nil)
 			c.Abort()
 			return
 		}
 
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			utils.Error("Invalid authorization header", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Invalid authorization header", map[string]interface{}{
 				"path": c.Request.URL.Path,
 			})
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "Invalid authorization header",
-			})
+			utils.RespondWithError(c, utils.ErrAuth, "Invalid authorization header", nil)
 			c.Abort()
 			return
 		}
@@ -47,41 +45,55 @@ func AuthMiddleware(authClient grpc.AuthClient) gin.HandlerFunc {
 
 		resp, err := authClient.VerifyToken(c.Request.Context(), &proto.VerifyTokenRequest{Token: token})
 		if err != nil {
-			utils.Error("Failed to verify token", map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), "Failed to verify token", map[string]interface{}{
 				"error": err,
 			})
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse{
-				Type:    "AUTH_ERROR",
-				Message: "Failed to verify token",
-			})
+			utils.RespondWithGrpcError(c, err, "Failed to verify token")
 			c.Abort()
 			return
 		}
 
 		if !resp.Success {
-			utils.Error(resp.Message, map[string]interface{}{
+			utils.ErrorContext(c.Request.Context(), resp.Message, map[string]interface{}{
 				"path": c.Request.URL.Path,
 			})
 			if resp.Error != nil {
 				errorResp, statusCode := utils.ConvertProtoErrorToResponse(resp.Error)
-				c.JSON(statusCode, errorResp)
+				utils.WriteError(c, statusCode, errorResp)
 				c.Abort()
 				return
 			}
 
 			// Fallback if error structure is not available
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse{
-				Type:    "UNKNOWN_ERROR",
-				Message: resp.Message,
-			})
+			utils.RespondWithError(c, utils.ErrRequestFailed, resp.Message, nil)
 			c.Abort()
 			return
 		}
 
+		if revocations != nil {
+			var issuedAt time.Time
+			if resp.IssuedAt > 0 {
+				issuedAt = time.Unix(resp.IssuedAt, 0)
+			}
+			if revocations.IsRevoked(resp.TokenId, resp.UserId, issuedAt) {
+				utils.WarnContext(c.Request.Context(), "Token has been revoked", map[string]interface{}{
+					"path": c.Request.URL.Path,
+				})
+				utils.RespondWithError(c, utils.ErrAuth, "Token has been revoked", nil)
+				c.Abort()
+				return
+			}
+		}
+
 		c.Set("user_id", resp.UserId)
 		c.Set("user_role", resp.Role)
+		c.Set("token", token)
+		c.Set("token_id", resp.TokenId)
+		c.Set("token_expires_at", resp.ExpiresAt)
+		c.Set("user_mfa", resp.Mfa)
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("user.role", resp.Role))
 
-		utils.Info("User authenticated", map[string]interface{}{
+		utils.InfoContext(c.Request.Context(), "User authenticated", map[string]interface{}{
 			"user_id":   resp.UserId,
 			"user_role": resp.Role,
 		})
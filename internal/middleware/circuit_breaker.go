@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CircuitBreakerMiddleware rejects requests up front while the circuit
+// breaker of the backend service they depend on is open, instead of letting
+// every request wait for the backend to time out.
+func CircuitBreakerMiddleware(breaker *grpc.CircuitBreaker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if breaker == nil || !breaker.IsOpen() {
+			c.Next()
+			return
+		}
+
+		retryAfter := breaker.RetryAfter()
+		utils.WarnContext(c.Request.Context(), "Rejecting request, circuit breaker is open", map[string]interface{}{
+			"path":        c.Request.URL.Path,
+			"service":     breaker.Service(),
+			"retry_after": retryAfter.String(),
+		})
+
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		utils.RespondWithError(c, utils.ErrUnavailable, "Service temporarily unavailable, please retry later", map[string]string{"service": breaker.Service()})
+		c.Abort()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that did not match any route.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records request counts, latency and in-flight requests
+// labelled by route template.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+
+		inFlight := utils.HTTPRequestsInFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		status := strconv.Itoa(c.Writer.Status())
+		utils.HTTPRequestsTotal.WithLabelValues(method, route, status).Inc()
+		utils.HTTPRequestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionMiddleware allows the request when the caller's role is granted
+// the permission by the current policy and, for roles the enforcer requires
+// MFA for, the session passed a second factor. It must run after
+// AuthMiddleware.
+func PermissionMiddleware(enforcer *rbac.Enforcer, permission string) gin.HandlerFunc {
+	required, err := rbac.ParsePermission(permission)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(c *gin.Context) {
+		role, exists := c.Get("user_role")
+		if !exists {
+			utils.ErrorContext(c.Request.Context(), "User not authenticated", map[string]interface{}{
+				"path": c.Request.URL.Path,
+			})
+			utils.RespondWithError(c, utils.ErrAuth, "User not authenticated", nil)
+			c.Abort()
+			return
+		}
+
+		scope, ok := enforcer.Policy().Scope(role.(string), required)
+		if !ok {
+			utils.WarnContext(c.Request.Context(), "User not authorized", map[string]interface{}{
+				"path":       c.Request.URL.Path,
+				"user_role":  role,
+				"permission": permission,
+			})
+			utils.RespondWithError(c, utils.ErrForbidden, "User not authorized", map[string]string{"permission": permission})
+			c.Abort()
+			return
+		}
+
+		if !checkMFA(c, enforcer) {
+			return
+		}
+
+		c.Set(rbac.ScopeKey, scope)
+		c.Next()
+	}
+}
+
+// checkMFA rejects sessions without a second factor for roles that require
+// one. It reports whether the request may continue.
+func checkMFA(c *gin.Context, enforcer *rbac.Enforcer) bool {
+	if !enforcer.RequiresMFA(c.GetString("user_role")) || c.GetBool("user_mfa") {
+		return true
+	}
+
+	utils.WarnContext(c.Request.Context(), "Multi-factor authentication required", map[string]interface{}{
+		"path":    c.Request.URL.Path,
+		"user_id": c.GetString("user_id"),
+	})
+	utils.RespondWithError(c, utils.ErrMFARequired, "Sign in with multi-factor authentication to access this resource", nil)
+	c.Abort()
+	return false
+}
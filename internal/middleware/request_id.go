@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRequestIDLength bounds client supplied request IDs so they cannot be
+// used to bloat logs and backend metadata.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware accepts the client's X-Request-ID or generates one,
+// stores it in the gin and request contexts and echoes it in the response.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(utils.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = utils.NewRequestID()
+		}
+
+		c.Set(utils.RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), requestID))
+		c.Header(utils.RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// validRequestID reports whether id is non-empty, reasonably short and made
+// of printable ASCII only, so it is safe to log and forward as metadata.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// FileSender appends every message as a JSON line to a local file, so the
+// links in them can be followed during development.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+		Message
+	}{time.Now().UTC(), msg})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// LogSender only logs that a message would have been sent. The body, which
+// holds single-use tokens, is left out.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	utils.InfoContext(ctx, "Notification not delivered, log sender configured", map[string]interface{}{
+		"subject": msg.Subject,
+	})
+	return nil
+}
@@ -0,0 +1,36 @@
+// Package notify delivers transactional messages such as verification and
+// password reset emails.
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender creates the sender selected by the configuration.
+func NewSender(cfg config.NotificationConfig) (Sender, error) {
+	switch cfg.Sender {
+	case "smtp":
+		return NewSMTPSender(cfg)
+	case "file":
+		return NewFileSender(cfg.FilePath), nil
+	case "", "log":
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification sender %q", cfg.Sender)
+	}
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+// SMTPSender sends messages through an SMTP relay, authenticating with PLAIN
+// auth when a username is configured. net/smtp upgrades to TLS with STARTTLS
+// whenever the server offers it, and refuses PLAIN auth without TLS.
+type SMTPSender struct {
+	addr string
+	from *mail.Address
+	auth smtp.Auth
+}
+
+// NewSMTPSender sends as cfg.From, e.g. "PharmaKart <no-reply@example.com>".
+// Only its address is used as the envelope sender.
+func NewSMTPSender(cfg config.NotificationConfig) (*SMTPSender, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFICATION_FROM %q: %w", cfg.From, err)
+	}
+
+	sender := &SMTPSender{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from: from,
+	}
+	if cfg.SMTPUsername != "" {
+		sender.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return sender, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid message header")
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(&body, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	// smtp.SendMail takes no context, so honour cancellation up front only.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(s.addr, s.auth, s.from.Address, []string{msg.To}, []byte(body.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
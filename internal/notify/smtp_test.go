@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+// fakeSMTPServer accepts one message and records the commands and data.
+type fakeSMTPServer struct {
+	listener net.Listener
+	commands chan string
+	data     chan string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTPServer{listener: listener, commands: make(chan string, 16), data: make(chan string, 1)}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		s.commands <- command
+
+		switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data <- data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	sender, err := NewSMTPSender(config.NotificationConfig{
+		SMTPHost: host,
+		SMTPPort: portNumber,
+		From:     "PharmaKart <no-reply@pharmakart.local>",
+	})
+	if err != nil {
+		t.Fatalf("failed to create sender: %v", err)
+	}
+
+	err = sender.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hello", Body: "Hi Jane\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var mailFrom string
+	for len(server.commands) > 0 {
+		if command := <-server.commands; strings.HasPrefix(command, "MAIL FROM:") {
+			mailFrom = command
+		}
+	}
+	if !strings.HasPrefix(mailFrom, "MAIL FROM:<no-reply@pharmakart.local>") {
+		t.Errorf("got %q, want the bare address as envelope sender", mailFrom)
+	}
+
+	data := <-server.data
+	if !strings.Contains(data, "From: \"PharmaKart\" <no-reply@pharmakart.local>\r\n") {
+		t.Errorf("message lacks the full From header:\n%s", data)
+	}
+}
+
+func TestNewSMTPSenderInvalidFrom(t *testing.T) {
+	if _, err := NewSMTPSender(config.NotificationConfig{From: "PharmaKart"}); err == nil {
+		t.Error("expected an error for a From without an address")
+	}
+}
@@ -0,0 +1,151 @@
+package rbac
+
+import (
+	_ "embed"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+//go:embed default_policy.yaml
+var defaultPolicy []byte
+
+// Enforcer holds the active policy. A policy loaded from a file is reloaded
+// whenever the file changes, or on demand; a policy that fails to parse is
+// rejected and the previous one stays in effect.
+type Enforcer struct {
+	cfg      config.RBACConfig
+	policy   atomic.Pointer[Policy]
+	mfaRoles map[string]bool
+
+	mu      sync.Mutex
+	modTime time.Time
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewEnforcer loads the policy file, or the built-in policy when none is
+// configured, and starts watching the file for changes. Sessions of mfaRoles
+// must have been established with a second factor.
+func NewEnforcer(cfg config.RBACConfig, mfaRoles []string) (*Enforcer, error) {
+	e := &Enforcer{
+		cfg:      cfg,
+		mfaRoles: roleSet(mfaRoles),
+		done:     make(chan struct{}),
+	}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+
+	if cfg.PolicyFile != "" && cfg.ReloadInterval > 0 {
+		go e.watch()
+	}
+
+	return e, nil
+}
+
+// NewStaticEnforcer creates an enforcer for a fixed policy.
+func NewStaticEnforcer(policy *Policy, mfaRoles ...string) *Enforcer {
+	e := &Enforcer{mfaRoles: roleSet(mfaRoles), done: make(chan struct{})}
+	e.policy.Store(policy)
+	return e
+}
+
+func roleSet(roles []string) map[string]bool {
+	set := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		set[role] = true
+	}
+	return set
+}
+
+// Policy returns the active policy.
+func (e *Enforcer) Policy() *Policy {
+	return e.policy.Load()
+}
+
+// RequiresMFA reports whether sessions of role must have passed a second
+// factor.
+func (e *Enforcer) RequiresMFA(role string) bool {
+	return e.mfaRoles[role]
+}
+
+// Reload reads and activates the policy file.
+func (e *Enforcer) Reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cfg.PolicyFile == "" {
+		policy, err := ParsePolicy(defaultPolicy)
+		if err != nil {
+			return err
+		}
+		e.policy.Store(policy)
+		return nil
+	}
+
+	info, err := os.Stat(e.cfg.PolicyFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(e.cfg.PolicyFile)
+	if err != nil {
+		return err
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return err
+	}
+
+	e.policy.Store(policy)
+	e.modTime = info.ModTime()
+	return nil
+}
+
+func (e *Enforcer) changed() bool {
+	info, err := os.Stat(e.cfg.PolicyFile)
+	if err != nil {
+		// A deploy in progress may briefly remove the file.
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !info.ModTime().Equal(e.modTime)
+}
+
+func (e *Enforcer) watch() {
+	ticker := time.NewTicker(e.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			if !e.changed() {
+				continue
+			}
+			if err := e.Reload(); err != nil {
+				utils.Error("Failed to reload RBAC policy", map[string]interface{}{
+					"error": err,
+					"file":  e.cfg.PolicyFile,
+				})
+				continue
+			}
+			utils.Info("Reloaded RBAC policy", map[string]interface{}{
+				"file": e.cfg.PolicyFile,
+			})
+		}
+	}
+}
+
+// Close stops watching the policy file.
+func (e *Enforcer) Close() {
+	e.once.Do(func() { close(e.done) })
+}
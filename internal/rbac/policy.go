@@ -0,0 +1,150 @@
+// Package rbac evaluates the role-based access policy: which permissions each
+// role is granted.
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes limit a permission to the caller's own resources or extend it to
+// every resource. A permission granted without a scope applies to any.
+const (
+	ScopeOwn = "own"
+	ScopeAny = "any"
+)
+
+// ScopeKey is the gin context key holding the scope in which the caller was
+// granted the route's permission.
+const ScopeKey = "permission_scope"
+
+// Permission is a parsed "resource:action[:scope]" string. Resource and
+// action may be "*" to match anything.
+type Permission struct {
+	Resource string
+	Action   string
+	Scope    string
+}
+
+// ParsePermission parses a permission. An empty scope is left empty so
+// callers can tell a scoped requirement from an unscoped one.
+func ParsePermission(s string) (Permission, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Permission{}, fmt.Errorf("invalid permission %q, want resource:action[:scope]", s)
+	}
+
+	p := Permission{Resource: parts[0], Action: parts[1]}
+	if len(parts) == 3 {
+		p.Scope = parts[2]
+	}
+	if p.Resource == "" || p.Action == "" {
+		return Permission{}, fmt.Errorf("invalid permission %q, want resource:action[:scope]", s)
+	}
+	switch p.Scope {
+	case "", ScopeOwn, ScopeAny:
+	default:
+		return Permission{}, fmt.Errorf("invalid scope %q in permission %q, want %s or %s", p.Scope, s, ScopeOwn, ScopeAny)
+	}
+	return p, nil
+}
+
+func (p Permission) String() string {
+	if p.Scope == "" {
+		return p.Resource + ":" + p.Action
+	}
+	return p.Resource + ":" + p.Action + ":" + p.Scope
+}
+
+// grantScope returns the scope this grant gives for resource and action, or
+// "" when it does not cover them.
+func (p Permission) grantScope(resource, action string) string {
+	if (p.Resource != "*" && p.Resource != resource) || (p.Action != "*" && p.Action != action) {
+		return ""
+	}
+	if p.Scope == "" {
+		return ScopeAny
+	}
+	return p.Scope
+}
+
+// Policy maps roles to the permissions granted to them.
+type Policy struct {
+	roles map[string][]Permission
+}
+
+type policyFile struct {
+	Roles map[string][]string `yaml:"roles" json:"roles"`
+}
+
+// ParsePolicy parses a YAML or JSON policy document of the form
+//
+//	roles:
+//	  customer:
+//	    - orders:read:own
+func ParsePolicy(data []byte) (*Policy, error) {
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse RBAC policy: %w", err)
+	}
+	if len(file.Roles) == 0 {
+		return nil, fmt.Errorf("RBAC policy defines no roles")
+	}
+
+	policy := &Policy{roles: make(map[string][]Permission, len(file.Roles))}
+	for role, grants := range file.Roles {
+		permissions := make([]Permission, 0, len(grants))
+		for _, grant := range grants {
+			p, err := ParsePermission(grant)
+			if err != nil {
+				return nil, fmt.Errorf("role %s: %w", role, err)
+			}
+			permissions = append(permissions, p)
+		}
+		policy.roles[role] = permissions
+	}
+	return policy, nil
+}
+
+// Scope returns the widest scope in which role holds the permission's
+// resource and action, or false when it does not hold them. When the
+// required permission has a scope, only grants of that scope or wider count.
+func (p *Policy) Scope(role string, required Permission) (string, bool) {
+	scope := ""
+	for _, grant := range p.roles[role] {
+		switch grant.grantScope(required.Resource, required.Action) {
+		case ScopeAny:
+			return ScopeAny, true
+		case ScopeOwn:
+			scope = ScopeOwn
+		}
+	}
+
+	if scope == "" || (required.Scope == ScopeAny && scope != ScopeAny) {
+		return "", false
+	}
+	return scope, true
+}
+
+// Allows reports whether role holds the permission.
+func (p *Policy) Allows(role string, required Permission) bool {
+	_, ok := p.Scope(role, required)
+	return ok
+}
+
+// Roles returns the policy as role to permission strings.
+func (p *Policy) Roles() map[string][]string {
+	roles := make(map[string][]string, len(p.roles))
+	for role, permissions := range p.roles {
+		grants := make([]string, 0, len(permissions))
+		for _, permission := range permissions {
+			grants = append(grants, permission.String())
+		}
+		sort.Strings(grants)
+		roles[role] = grants
+	}
+	return roles
+}
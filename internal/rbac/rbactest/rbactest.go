@@ -0,0 +1,170 @@
+// Package rbactest checks a router's authorization against an expected
+// route × role matrix.
+//
+// A test builds the gateway router with AuthClient, which signs in any
+// bearer token of the form "role:<name>", and passes it with the matrix:
+//
+//	rbactest.AssertRouteMatrix(t, router, []string{"customer", "admin"}, []rbactest.Route{
+//		{Method: "GET", Path: "/api/v1/admin/orders", Allowed: []string{"admin"}},
+//		{Method: "GET", Path: "/api/v1/products", Public: true},
+//	})
+package rbactest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenPrefix starts every bearer token accepted by AuthClient.
+const TokenPrefix = "role:"
+
+// withoutMFASuffix ends the tokens of sessions that did not pass MFA.
+const withoutMFASuffix = "+nomfa"
+
+// Token returns the bearer token AuthClient accepts for role.
+func Token(role string) string {
+	return TokenPrefix + role
+}
+
+// TokenWithoutMFA returns the bearer token of a role's session that was
+// established without a second factor.
+func TokenWithoutMFA(role string) string {
+	return TokenPrefix + role + withoutMFASuffix
+}
+
+// AuthClient verifies tokens created by Token, as sessions that passed MFA,
+// and those created by TokenWithoutMFA, as sessions that did not. Its other
+// methods are not implemented and panic.
+type AuthClient struct {
+	grpc.AuthClient
+}
+
+func (AuthClient) VerifyToken(ctx context.Context, req *proto.VerifyTokenRequest) (*proto.VerifyTokenResponse, error) {
+	role, ok := strings.CutPrefix(req.GetToken(), TokenPrefix)
+	role, withoutMFA := strings.CutSuffix(role, withoutMFASuffix)
+	if !ok || role == "" {
+		return &proto.VerifyTokenResponse{
+			Success: false,
+			Message: "Invalid token",
+			Error:   &proto.Error{Type: "AUTH_ERROR", Message: "Invalid token"},
+		}, nil
+	}
+
+	return &proto.VerifyTokenResponse{
+		Success: true,
+		UserId:  "rbactest-" + role,
+		Role:    role,
+		TokenId: req.GetToken(),
+		Mfa:     !withoutMFA,
+	}, nil
+}
+
+// Route is one row of the matrix. Path is the route template as registered,
+// e.g. "/api/v1/orders/:id". Public routes need no token and are expected to
+// be reachable by everyone; otherwise only the Allowed roles may pass.
+type Route struct {
+	Method  string
+	Path    string
+	Public  bool
+	Allowed []string
+}
+
+// AssertRouteMatrix requests every route once per role and fails the test
+// when a role is let through or refused contrary to the matrix. A request
+// counts as refused when it is answered with 401 or 403; anything else,
+// including errors from the handler, means authorization let it through.
+//
+// When handler is a *gin.Engine the matrix must list exactly the registered
+// routes, so new routes cannot be added without deciding who may call them.
+func AssertRouteMatrix(t testing.TB, handler http.Handler, roles []string, routes []Route) {
+	t.Helper()
+
+	if engine, ok := handler.(*gin.Engine); ok {
+		assertComplete(t, engine, routes)
+	}
+
+	for _, route := range routes {
+		allowed := make(map[string]bool, len(route.Allowed))
+		for _, role := range route.Allowed {
+			allowed[role] = true
+		}
+
+		if !route.Public {
+			if status := serve(handler, route, ""); !refused(status) {
+				t.Errorf("%s %s without a token: got status %d, want 401", route.Method, route.Path, status)
+			}
+		}
+
+		for _, role := range roles {
+			want := route.Public || allowed[role]
+			status := serve(handler, route, Token(role))
+			if got := !refused(status); got != want {
+				t.Errorf("%s %s as %s: got status %d, want allowed=%t", route.Method, route.Path, role, status, want)
+			}
+		}
+	}
+}
+
+func assertComplete(t testing.TB, engine *gin.Engine, routes []Route) {
+	t.Helper()
+
+	covered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		covered[route.Method+" "+route.Path] = true
+	}
+
+	registered := make(map[string]bool)
+	var missing []string
+	for _, info := range engine.Routes() {
+		key := info.Method + " " + info.Path
+		registered[key] = true
+		if !covered[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, key := range missing {
+		t.Errorf("route %s is missing from the RBAC matrix", key)
+	}
+	for _, route := range routes {
+		if key := route.Method + " " + route.Path; !registered[key] {
+			t.Errorf("route %s in the RBAC matrix is not registered", key)
+		}
+	}
+}
+
+func serve(handler http.Handler, route Route, token string) int {
+	req := httptest.NewRequest(route.Method, samplePath(route.Path), nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// samplePath fills the parameters of a route template with placeholder
+// values.
+func samplePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "rbactest"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func refused(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
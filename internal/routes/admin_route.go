@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/PharmaKart/gateway-svc/internal/audit"
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/handlers"
+	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminRoutes(r *gin.RouterGroup, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, breakers grpc.CircuitBreakers, auditSink audit.Sink) {
+	admin := r.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(authClient, revocations))
+	{
+		admin.GET("/circuit-breakers", middleware.PermissionMiddleware(enforcer, "system:read"), handlers.ListCircuitBreakers(breakers))
+		admin.GET("/audit", middleware.PermissionMiddleware(enforcer, "audit:read"), handlers.ListAuditEvents(auditSink))
+		admin.GET("/audit/verify", middleware.PermissionMiddleware(enforcer, "audit:read"), handlers.VerifyAuditLog(auditSink))
+		admin.GET("/rbac/policy", middleware.PermissionMiddleware(enforcer, "policy:read"), handlers.GetRBACPolicy(enforcer))
+		admin.POST("/rbac/reload", middleware.PermissionMiddleware(enforcer, "policy:write"), middleware.AuditMiddleware(auditSink), handlers.ReloadRBACPolicy(enforcer))
+	}
+}
@@ -3,10 +3,34 @@ package routes
 import (
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/handlers"
+	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/notify"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterAuthRoutes(r *gin.RouterGroup, authClient grpc.AuthClient) {
+func RegisterAuthRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, revocations *grpc.RevocationList, sender notify.Sender, breaker *grpc.CircuitBreaker) {
+	r = r.Group("", middleware.CircuitBreakerMiddleware(breaker))
+
 	r.POST("/register", handlers.Register(authClient))
 	r.POST("/login", handlers.Login(authClient))
+	r.POST("/login/mfa", handlers.VerifyMFA(authClient, handlers.NewMFALimiter(cfg.MFA)))
+	r.POST("/token/refresh", handlers.RefreshToken(authClient))
+	r.POST("/logout", middleware.AuthMiddleware(authClient, revocations), handlers.Logout(authClient, revocations))
+
+	limiter := handlers.NewAccountLimiter(cfg.AccountRateLimit)
+	r.POST("/email/verification", handlers.RequestEmailVerification(authClient, sender, limiter, cfg.Notification))
+	r.POST("/email/verification/confirm", handlers.VerifyEmail(authClient))
+	r.POST("/password/reset", handlers.RequestPasswordReset(authClient, sender, limiter, cfg.Notification))
+	r.POST("/password/reset/confirm", handlers.ResetPassword(authClient, revocations))
+
+	// Any signed-in user manages their own MFA, even before a role that
+	// requires MFA lets them past PermissionMiddleware.
+	mfa := r.Group("/mfa", middleware.AuthMiddleware(authClient, revocations))
+	{
+		mfa.POST("/enroll", handlers.EnrollMFA(authClient))
+		mfa.POST("/confirm", handlers.ConfirmMFA(authClient))
+		mfa.POST("/disable", handlers.DisableMFA(authClient))
+		mfa.POST("/recovery-codes", handlers.RegenerateRecoveryCodes(authClient))
+	}
 }
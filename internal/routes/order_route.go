@@ -1,29 +1,47 @@
 package routes
 
 import (
+	"github.com/PharmaKart/gateway-svc/internal/audit"
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/handlers"
 	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterOrderRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, orderClient grpc.OrderClient, paymentClient grpc.PaymentClient) {
-	r.Use(middleware.AuthMiddleware(authClient))
+func RegisterOrderRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, stores *storage.Stores, guard *scan.Guard, receipts *upload.Receipts, orderClient grpc.OrderClient, paymentClient grpc.PaymentClient, auditSink audit.Sink, breaker *grpc.CircuitBreaker) {
+	r = r.Group("", middleware.CircuitBreakerMiddleware(breaker))
+
+	r.Use(middleware.AuthMiddleware(authClient, revocations))
 	{
-		r.POST("/orders", handlers.PlaceOrder(cfg, orderClient))
-		r.GET("/orders", handlers.ListCustomersOrders(orderClient))
-		r.GET("/orders/:id", handlers.GetOrder(orderClient, paymentClient))
-		r.PUT("/orders/:id", handlers.UpdateOrderStatus(orderClient))
-		r.POST("/orders/:id/payment", handlers.GenerateNewPaymentUrl(orderClient))
+		r.POST("/orders", middleware.PermissionMiddleware(enforcer, "orders:create"), middleware.PrescriptionAuditMiddleware(auditSink), handlers.PlaceOrder(cfg, stores.Prescriptions, guard, receipts, orderClient))
+		r.GET("/orders", middleware.PermissionMiddleware(enforcer, "orders:read"), handlers.ListCustomersOrders(orderClient))
+		r.GET("/orders/:id", middleware.PermissionMiddleware(enforcer, "orders:read"), handlers.GetOrder(orderClient, paymentClient))
+		r.GET("/orders/:id/prescription", middleware.AccessAuditMiddleware(auditSink), middleware.PermissionMiddleware(enforcer, "prescriptions:read"), handlers.GetOrderPrescription(cfg, stores, orderClient))
+		r.PUT("/orders/:id", middleware.PermissionMiddleware(enforcer, "orders:update"), handlers.UpdateOrderStatus(orderClient))
+		r.POST("/orders/:id/payment", middleware.PermissionMiddleware(enforcer, "orders:pay"), handlers.GenerateNewPaymentUrl(orderClient))
 	}
 
+	// The group above already authenticates admin routes
 	admin := r.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(authClient))
-	admin.Use(middleware.RBACMiddleware("admin"))
+	admin.Use(middleware.AuditMiddleware(auditSink))
+	{
+		admin.GET("/orders", middleware.PermissionMiddleware(enforcer, "orders:read:any"), handlers.ListAllOrders(orderClient))
+		admin.GET("/orders/:id", middleware.PermissionMiddleware(enforcer, "orders:read:any"), handlers.GetOrder(orderClient, paymentClient))
+		admin.PUT("/orders/:id", middleware.PermissionMiddleware(enforcer, "orders:update:any"), handlers.UpdateOrderStatus(orderClient))
+	}
+
+	// The group above already authenticates pharmacist routes
+	pharmacist := r.Group("/pharmacist")
+	pharmacist.Use(middleware.AccessAuditMiddleware(auditSink))
 	{
-		admin.GET("/orders", handlers.ListAllOrders(orderClient))
-		admin.GET("/orders/:id", handlers.GetOrder(orderClient, paymentClient))
-		admin.PUT("/orders/:id", handlers.UpdateOrderStatus(orderClient))
+		pharmacist.GET("/prescriptions", middleware.PermissionMiddleware(enforcer, "prescriptions:read:any"), handlers.ListPrescriptionReviews(orderClient))
+		pharmacist.GET("/prescriptions/:id", middleware.PermissionMiddleware(enforcer, "prescriptions:read:any"), handlers.GetPrescriptionReview(orderClient))
+		pharmacist.POST("/prescriptions/:id/approve", middleware.PermissionMiddleware(enforcer, "prescriptions:review"), handlers.ApprovePrescription(orderClient))
+		pharmacist.POST("/prescriptions/:id/reject", middleware.PermissionMiddleware(enforcer, "prescriptions:review"), handlers.RejectPrescription(orderClient))
 	}
 }
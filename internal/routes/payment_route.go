@@ -4,17 +4,20 @@ import (
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/handlers"
 	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterPaymentRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, paymentClient grpc.PaymentClient) {
+func RegisterPaymentRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, paymentClient grpc.PaymentClient, breaker *grpc.CircuitBreaker) {
+	r = r.Group("", middleware.CircuitBreakerMiddleware(breaker))
+
 	r.POST("/payment/webhook", handlers.HandleWebhook(cfg, paymentClient))
 
-	r.Use(middleware.AuthMiddleware(authClient))
+	r.Use(middleware.AuthMiddleware(authClient, revocations))
 	{
-		r.GET("/payment/:id", handlers.GetPayment(paymentClient))
-		r.GET("/payment/order/:id", handlers.GetPaymentByOrderID(paymentClient))
+		r.GET("/payment/:id", middleware.PermissionMiddleware(enforcer, "payments:read"), handlers.GetPayment(paymentClient))
+		r.GET("/payment/order/:id", middleware.PermissionMiddleware(enforcer, "payments:read"), handlers.GetPaymentByOrderID(paymentClient))
 	}
 
 }
@@ -1,25 +1,32 @@
 package routes
 
 import (
+	"github.com/PharmaKart/gateway-svc/internal/audit"
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/handlers"
 	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterProductRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, productClient grpc.ProductClient) {
+func RegisterProductRoutes(r *gin.RouterGroup, cfg *config.Config, images storage.BlobStore, guard *scan.Guard, receipts *upload.Receipts, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, productClient grpc.ProductClient, auditSink audit.Sink, breaker *grpc.CircuitBreaker) {
+	r = r.Group("", middleware.CircuitBreakerMiddleware(breaker))
+
 	r.GET("/products", handlers.GetProducts(productClient))
 	r.GET("/products/:id", handlers.GetProduct(productClient))
 
 	admin := r.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(authClient))
-	admin.Use(middleware.RBACMiddleware("admin"))
+	admin.Use(middleware.AuthMiddleware(authClient, revocations))
+	admin.Use(middleware.AuditMiddleware(auditSink))
 	{
-		admin.POST("/products", handlers.CreateProduct(cfg, productClient))
-		admin.PUT("/products/:id", handlers.UpdateProduct(cfg, productClient))
-		admin.DELETE("/products/:id", handlers.DeleteProduct(productClient))
-		admin.PUT("/products/:id/stock", handlers.UpdateStock(productClient))
-		admin.GET("/products/:id/logs", handlers.GetInventoryLogs(productClient))
+		admin.POST("/products", middleware.PermissionMiddleware(enforcer, "products:write"), handlers.CreateProduct(cfg, images, guard, receipts, productClient))
+		admin.PUT("/products/:id", middleware.PermissionMiddleware(enforcer, "products:write"), handlers.UpdateProduct(cfg, images, guard, receipts, productClient))
+		admin.DELETE("/products/:id", middleware.PermissionMiddleware(enforcer, "products:write"), handlers.DeleteProduct(productClient))
+		admin.PUT("/products/:id/stock", middleware.PermissionMiddleware(enforcer, "inventory:write"), handlers.UpdateStock(productClient))
+		admin.GET("/products/:id/logs", middleware.PermissionMiddleware(enforcer, "inventory:read"), handlers.GetInventoryLogs(productClient))
 	}
 }
@@ -4,25 +4,27 @@ import (
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/handlers"
 	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterReminderRoutes(r *gin.RouterGroup, authClient grpc.AuthClient, reminderClient grpc.ReminderClient) {
-	r.Use(middleware.AuthMiddleware(authClient))
+func RegisterReminderRoutes(r *gin.RouterGroup, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, reminderClient grpc.ReminderClient, breaker *grpc.CircuitBreaker) {
+	r = r.Group("", middleware.CircuitBreakerMiddleware(breaker))
+
+	r.Use(middleware.AuthMiddleware(authClient, revocations))
 	{
-		r.POST("/reminders", handlers.ScheduleReminder(reminderClient))
-		r.GET("/reminders", handlers.ListCustomerReminders(reminderClient))
-		r.PUT("/reminders/:id", handlers.UpdateReminder(reminderClient))
-		r.DELETE("/reminders/:id", handlers.DeleteReminder(reminderClient))
-		r.PATCH("/reminders/:id", handlers.ToggleReminder(reminderClient))
-		r.GET("/reminders/:id/logs", handlers.ListReminderLogs(reminderClient))
+		r.POST("/reminders", middleware.PermissionMiddleware(enforcer, "reminders:write"), handlers.ScheduleReminder(reminderClient))
+		r.GET("/reminders", middleware.PermissionMiddleware(enforcer, "reminders:read"), handlers.ListCustomerReminders(reminderClient))
+		r.PUT("/reminders/:id", middleware.PermissionMiddleware(enforcer, "reminders:write"), handlers.UpdateReminder(reminderClient))
+		r.DELETE("/reminders/:id", middleware.PermissionMiddleware(enforcer, "reminders:write"), handlers.DeleteReminder(reminderClient))
+		r.PATCH("/reminders/:id", middleware.PermissionMiddleware(enforcer, "reminders:write"), handlers.ToggleReminder(reminderClient))
+		r.GET("/reminders/:id/logs", middleware.PermissionMiddleware(enforcer, "reminders:read"), handlers.ListReminderLogs(reminderClient))
 	}
 
+	// The group above already authenticates admin routes
 	admin := r.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(authClient))
-	admin.Use(middleware.RBACMiddleware("admin"))
 	{
-		admin.GET("/reminders", handlers.ListReminders(reminderClient))
+		admin.GET("/reminders", middleware.PermissionMiddleware(enforcer, "reminders:read:any"), handlers.ListReminders(reminderClient))
 	}
 
 }
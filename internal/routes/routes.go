@@ -1,10 +1,17 @@
 package routes
 
 import (
+	"github.com/PharmaKart/gateway-svc/internal/audit"
 	"github.com/PharmaKart/gateway-svc/internal/grpc"
 	"github.com/PharmaKart/gateway-svc/internal/handlers"
+	"github.com/PharmaKart/gateway-svc/internal/notify"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
 	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // RegisterRoutes sets up all routes for the application.
@@ -18,23 +25,41 @@ import (
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 // @host localhost:8080
 // @BasePath /
-func RegisterRoutes(r *gin.Engine, cfg *config.Config, authClient grpc.AuthClient, productClient grpc.ProductClient, orderClient grpc.OrderClient, paymentClient grpc.PaymentClient, reminderClient grpc.ReminderClient) {
+func RegisterRoutes(r *gin.Engine, cfg *config.Config, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, productClient grpc.ProductClient, orderClient grpc.OrderClient, paymentClient grpc.PaymentClient, reminderClient grpc.ReminderClient, healthChecker grpc.HealthChecker, breakers grpc.CircuitBreakers, auditSink audit.Sink, sender notify.Sender, stores *storage.Stores, guard *scan.Guard, receipts *upload.Receipts) {
 	api := r.Group("/api/v1")
 	// Register auth routes
-	RegisterAuthRoutes(api, authClient)
+	RegisterAuthRoutes(api, cfg, authClient, revocations, sender, breakers["auth"])
 
 	// Register product routes
-	RegisterProductRoutes(api, cfg, authClient, productClient)
+	RegisterProductRoutes(api, cfg, stores.Images, guard, receipts, authClient, revocations, enforcer, productClient, auditSink, breakers["product"])
 
 	// Register order routes
-	RegisterOrderRoutes(api, cfg, authClient, orderClient, paymentClient)
+	RegisterOrderRoutes(api, cfg, authClient, revocations, enforcer, stores, guard, receipts, orderClient, paymentClient, auditSink, breakers["order"])
 
 	// Register payment routes
-	RegisterPaymentRoutes(api, cfg, authClient, paymentClient)
+	RegisterPaymentRoutes(api, cfg, authClient, revocations, enforcer, paymentClient, breakers["payment"])
 
 	// Register reminder routes
-	RegisterReminderRoutes(api, authClient, reminderClient)
+	RegisterReminderRoutes(api, authClient, revocations, enforcer, reminderClient, breakers["reminder"])
 
-	// Register health check route
+	// Register direct upload routes
+	RegisterUploadRoutes(api, cfg, authClient, revocations, enforcer, stores, guard, receipts, auditSink)
+
+	// Register admin routes
+	RegisterAdminRoutes(api, authClient, revocations, enforcer, breakers, auditSink)
+
+	// Serve files kept by the local storage driver
+	RegisterStorageRoutes(r, stores)
+
+	// Register health check routes
 	r.GET("/health", handlers.HealthCheck)
+	r.GET("/livez", handlers.Liveness)
+	r.GET("/readyz", handlers.Readiness(healthChecker))
+
+	// Register metrics route
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Register error catalog routes
+	r.GET("/problems", handlers.ListErrorCodes)
+	r.GET("/problems/:code", handlers.GetErrorCode)
 }
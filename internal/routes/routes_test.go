@@ -0,0 +1,209 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/internal/audit"
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/notify"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/internal/rbac/rbactest"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Backend clients that are not implemented. Requests that get past
+// authorization panic in the handler and are answered with a 500.
+type (
+	productClient  struct{ grpc.ProductClient }
+	orderClient    struct{ grpc.OrderClient }
+	paymentClient  struct{ grpc.PaymentClient }
+	reminderClient struct{ grpc.ReminderClient }
+)
+
+// newTestRouter builds the gateway router with the default RBAC policy.
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("RBAC_POLICY_FILE", "")
+	t.Setenv("S3_BUCKET_NAME", "images")
+	t.Setenv("PRESCRIPTION_BUCKET_NAME", "prescriptions")
+	t.Setenv("STORAGE_DRIVER", "local")
+	t.Setenv("STORAGE_LOCAL_DIR", filepath.Join(dir, "blobs"))
+	t.Setenv("STORAGE_SIGNING_KEY", "routes-test")
+	t.Setenv("SCAN_DRIVER", "none")
+	cfg := config.LoadConfig()
+	cfg.Logging.Level = "fatal"
+	utils.InitLogger(cfg.Logging)
+
+	enforcer, err := rbac.NewEnforcer(cfg.RBAC, cfg.MFA.RequiredRoles)
+	if err != nil {
+		t.Fatalf("failed to load default policy: %v", err)
+	}
+	breakers, err := grpc.NewCircuitBreakers(cfg.CircuitBreaker, "auth", "product", "order", "payment", "reminder")
+	if err != nil {
+		t.Fatalf("failed to create circuit breakers: %v", err)
+	}
+	auditSink, err := audit.NewFileSink(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatalf("failed to create audit sink: %v", err)
+	}
+	stores, err := storage.NewStores(cfg)
+	if err != nil {
+		t.Fatalf("failed to create blob stores: %v", err)
+	}
+	scanner, err := scan.NewScanner(cfg.Scan)
+	if err != nil {
+		t.Fatalf("failed to create scanner: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RecoveryMiddleware())
+	RegisterRoutes(r, cfg, rbactest.AuthClient{}, nil, enforcer,
+		productClient{}, orderClient{}, paymentClient{}, reminderClient{},
+		grpc.NewHealthChecker(cfg.ReadinessTimeout), breakers, auditSink,
+		notify.NewFileSender(filepath.Join(dir, "outbox.log")),
+		stores, scan.NewGuard(scanner, stores.Quarantine, false),
+		upload.NewReceipts(cfg.Upload.SigningKey, cfg.Upload.ReceiptTTL))
+	return r
+}
+
+func TestRouteMatrix(t *testing.T) {
+	r := newTestRouter(t)
+
+	const (
+		customer   = "customer"
+		admin      = "admin"
+		pharmacist = "pharmacist"
+		support    = "support"
+	)
+	everyone := []string{customer, admin, pharmacist, support}
+
+	rbactest.AssertRouteMatrix(t, r, everyone, []rbactest.Route{
+		// Authentication
+		{Method: "POST", Path: "/api/v1/register", Public: true},
+		{Method: "POST", Path: "/api/v1/login", Public: true},
+		{Method: "POST", Path: "/api/v1/login/mfa", Public: true},
+		{Method: "POST", Path: "/api/v1/token/refresh", Public: true},
+		{Method: "POST", Path: "/api/v1/logout", Allowed: everyone},
+		{Method: "POST", Path: "/api/v1/email/verification", Public: true},
+		{Method: "POST", Path: "/api/v1/email/verification/confirm", Public: true},
+		{Method: "POST", Path: "/api/v1/password/reset", Public: true},
+		{Method: "POST", Path: "/api/v1/password/reset/confirm", Public: true},
+		{Method: "POST", Path: "/api/v1/mfa/enroll", Allowed: everyone},
+		{Method: "POST", Path: "/api/v1/mfa/confirm", Allowed: everyone},
+		{Method: "POST", Path: "/api/v1/mfa/disable", Allowed: everyone},
+		{Method: "POST", Path: "/api/v1/mfa/recovery-codes", Allowed: everyone},
+
+		// Products
+		{Method: "GET", Path: "/api/v1/products", Public: true},
+		{Method: "GET", Path: "/api/v1/products/:id", Public: true},
+		{Method: "POST", Path: "/api/v1/admin/products", Allowed: []string{admin}},
+		{Method: "PUT", Path: "/api/v1/admin/products/:id", Allowed: []string{admin}},
+		{Method: "DELETE", Path: "/api/v1/admin/products/:id", Allowed: []string{admin}},
+		{Method: "PUT", Path: "/api/v1/admin/products/:id/stock", Allowed: []string{admin}},
+		{Method: "GET", Path: "/api/v1/admin/products/:id/logs", Allowed: []string{admin}},
+
+		// Orders
+		{Method: "POST", Path: "/api/v1/orders", Allowed: []string{customer}},
+		{Method: "GET", Path: "/api/v1/orders", Allowed: everyone},
+		{Method: "GET", Path: "/api/v1/orders/:id", Allowed: everyone},
+		{Method: "GET", Path: "/api/v1/orders/:id/prescription", Allowed: []string{customer, admin, pharmacist}},
+		{Method: "PUT", Path: "/api/v1/orders/:id", Allowed: []string{customer, admin}},
+		{Method: "POST", Path: "/api/v1/orders/:id/payment", Allowed: []string{customer}},
+		{Method: "GET", Path: "/api/v1/admin/orders", Allowed: []string{admin, pharmacist, support}},
+		{Method: "GET", Path: "/api/v1/admin/orders/:id", Allowed: []string{admin, pharmacist, support}},
+		{Method: "PUT", Path: "/api/v1/admin/orders/:id", Allowed: []string{admin}},
+
+		// Prescription review
+		{Method: "GET", Path: "/api/v1/pharmacist/prescriptions", Allowed: []string{admin, pharmacist}},
+		{Method: "GET", Path: "/api/v1/pharmacist/prescriptions/:id", Allowed: []string{admin, pharmacist}},
+		{Method: "POST", Path: "/api/v1/pharmacist/prescriptions/:id/approve", Allowed: []string{pharmacist}},
+		{Method: "POST", Path: "/api/v1/pharmacist/prescriptions/:id/reject", Allowed: []string{pharmacist}},
+
+		// Payments
+		{Method: "POST", Path: "/api/v1/payment/webhook", Public: true},
+		{Method: "GET", Path: "/api/v1/payment/:id", Allowed: []string{customer, admin, support}},
+		{Method: "GET", Path: "/api/v1/payment/order/:id", Allowed: []string{customer, admin, support}},
+
+		// Reminders
+		{Method: "POST", Path: "/api/v1/reminders", Allowed: []string{customer}},
+		{Method: "GET", Path: "/api/v1/reminders", Allowed: []string{customer, admin, support}},
+		{Method: "PUT", Path: "/api/v1/reminders/:id", Allowed: []string{customer}},
+		{Method: "DELETE", Path: "/api/v1/reminders/:id", Allowed: []string{customer}},
+		{Method: "PATCH", Path: "/api/v1/reminders/:id", Allowed: []string{customer}},
+		{Method: "GET", Path: "/api/v1/reminders/:id/logs", Allowed: []string{customer, admin, support}},
+		{Method: "GET", Path: "/api/v1/admin/reminders", Allowed: []string{admin, support}},
+
+		// Direct uploads
+		{Method: "POST", Path: "/api/v1/uploads/products", Allowed: []string{admin}},
+		{Method: "POST", Path: "/api/v1/uploads/products/confirm", Allowed: []string{admin}},
+		{Method: "POST", Path: "/api/v1/uploads/prescriptions", Allowed: []string{customer}},
+		{Method: "POST", Path: "/api/v1/uploads/prescriptions/confirm", Allowed: []string{customer}},
+
+		// Administration
+		{Method: "GET", Path: "/api/v1/admin/circuit-breakers", Allowed: []string{admin}},
+		{Method: "GET", Path: "/api/v1/admin/audit", Allowed: []string{admin}},
+		{Method: "GET", Path: "/api/v1/admin/audit/verify", Allowed: []string{admin}},
+		{Method: "GET", Path: "/api/v1/admin/rbac/policy", Allowed: []string{admin}},
+		{Method: "POST", Path: "/api/v1/admin/rbac/reload", Allowed: []string{admin}},
+
+		// Local blob storage, authorized by signature rather than role
+		{Method: "GET", Path: "/blobs/images/*key", Public: true},
+		{Method: "HEAD", Path: "/blobs/images/*key", Public: true},
+		{Method: "POST", Path: "/blobs/images/*key", Public: true},
+		{Method: "GET", Path: "/blobs/prescriptions/*key", Public: true},
+		{Method: "HEAD", Path: "/blobs/prescriptions/*key", Public: true},
+		{Method: "POST", Path: "/blobs/prescriptions/*key", Public: true},
+
+		// Operations
+		{Method: "GET", Path: "/health", Public: true},
+		{Method: "GET", Path: "/livez", Public: true},
+		{Method: "GET", Path: "/readyz", Public: true},
+		{Method: "GET", Path: "/metrics", Public: true},
+		{Method: "GET", Path: "/problems", Public: true},
+		{Method: "GET", Path: "/problems/:code", Public: true},
+	})
+}
+
+func TestMFARequiredRoles(t *testing.T) {
+	t.Setenv("MFA_REQUIRED_ROLES", "admin")
+	r := newTestRouter(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		token   string
+		refused bool
+	}{
+		{"admin with MFA", "/api/v1/admin/rbac/policy", rbactest.Token("admin"), false},
+		{"admin without MFA", "/api/v1/admin/rbac/policy", rbactest.TokenWithoutMFA("admin"), true},
+		{"customer without MFA", "/api/v1/orders", rbactest.TokenWithoutMFA("customer"), false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		refused := rec.Code == http.StatusForbidden
+		if refused != tt.refused {
+			t.Errorf("%s: got status %d, want refused=%t", tt.name, rec.Code, tt.refused)
+		}
+		if tt.refused && !strings.Contains(rec.Body.String(), utils.ErrMFARequired.Code) {
+			t.Errorf("%s: got body %s, want %s", tt.name, rec.Body, utils.ErrMFARequired.Code)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterStorageRoutes serves the objects of stores using the local driver,
+// and accepts presigned uploads to them. Stores sharing a bucket share a
+// directory, so it is served once.
+func RegisterStorageRoutes(r *gin.Engine, stores *storage.Stores) {
+	registered := make(map[string]bool)
+	for _, store := range []storage.BlobStore{stores.Images, stores.Prescriptions} {
+		local, ok := store.(*storage.LocalStore)
+		if !ok || registered[local.Prefix()] {
+			continue
+		}
+		registered[local.Prefix()] = true
+
+		r.GET(local.Prefix()+"*key", gin.WrapH(local))
+		r.HEAD(local.Prefix()+"*key", gin.WrapH(local))
+		r.POST(local.Prefix()+"*key", gin.WrapH(local))
+	}
+}
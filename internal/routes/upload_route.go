@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/PharmaKart/gateway-svc/internal/audit"
+	"github.com/PharmaKart/gateway-svc/internal/grpc"
+	"github.com/PharmaKart/gateway-svc/internal/handlers"
+	"github.com/PharmaKart/gateway-svc/internal/middleware"
+	"github.com/PharmaKart/gateway-svc/internal/rbac"
+	"github.com/PharmaKart/gateway-svc/internal/scan"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterUploadRoutes(r *gin.RouterGroup, cfg *config.Config, authClient grpc.AuthClient, revocations *grpc.RevocationList, enforcer *rbac.Enforcer, stores *storage.Stores, guard *scan.Guard, receipts *upload.Receipts, auditSink audit.Sink) {
+	images := handlers.ProductImageUploads(cfg, stores.Images)
+	prescriptions := handlers.PrescriptionUploads(cfg, stores.Prescriptions)
+
+	uploads := r.Group("/uploads")
+	uploads.Use(middleware.AuthMiddleware(authClient, revocations))
+	{
+		uploads.POST("/products", middleware.PermissionMiddleware(enforcer, "products:write"), handlers.CreateUploadURL(images))
+		uploads.POST("/products/confirm", middleware.PermissionMiddleware(enforcer, "products:write"), middleware.AuditMiddleware(auditSink), handlers.ConfirmUpload(images, guard, receipts))
+		uploads.POST("/prescriptions", middleware.PermissionMiddleware(enforcer, "orders:create"), handlers.CreateUploadURL(prescriptions))
+		uploads.POST("/prescriptions/confirm", middleware.PermissionMiddleware(enforcer, "orders:create"), middleware.AuditMiddleware(auditSink), handlers.ConfirmUpload(prescriptions, guard, receipts))
+	}
+}
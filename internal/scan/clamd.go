@@ -0,0 +1,156 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks streamed to clamd. It must stay
+// below clamd's StreamMaxLength.
+const clamdChunkSize = 64 * 1024
+
+// ClamdScanner scans files with a ClamAV daemon over its INSTREAM command.
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner connects to clamd at address, either "tcp://host:port" or
+// "unix:///path/to/clamd.sock". Every scan must finish within timeout.
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamd address %q: %w", address, err)
+	}
+
+	switch parsed.Scheme {
+	case "tcp":
+		return &ClamdScanner{network: "tcp", address: parsed.Host, timeout: timeout}, nil
+	case "unix":
+		return &ClamdScanner{network: "unix", address: parsed.Path, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("invalid clamd address %q: scheme must be tcp or unix", address)
+	}
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, body io.Reader) (Result, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	chunk := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := body.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return Result{}, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+			if _, err := conn.Write(chunk[:n]); err != nil {
+				return Result{}, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return Result{}, readErr
+		}
+	}
+	// A zero-length chunk ends the stream.
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Result{}, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return Result{}, err
+	}
+	return parseReply(reply)
+}
+
+// Ping checks that clamd is up.
+func (s *ClamdScanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+	return nil
+}
+
+// dial connects to clamd with a deadline for the whole exchange.
+func (s *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	deadline := time.Now().Add(s.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// readReply reads a NUL-terminated reply.
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return string(bytes.TrimRight(reply, "\x00")), nil
+}
+
+// parseReply interprets "stream: OK", "stream: <signature> FOUND" and
+// "<message> ERROR" replies.
+func parseReply(reply string) (Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return Result{}, fmt.Errorf("clamd error: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return Result{}, fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+}
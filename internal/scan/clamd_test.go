@@ -0,0 +1,123 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/scan/scantest"
+)
+
+func newClamdScanner(t *testing.T, address string) *ClamdScanner {
+	t.Helper()
+
+	scanner, err := NewClamdScanner(address, time.Second)
+	if err != nil {
+		t.Fatalf("failed to create scanner: %v", err)
+	}
+	return scanner
+}
+
+// unreachableAddress is a clamd address nothing listens on.
+func unreachableAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := "tcp://" + listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestClamdScannerScan(t *testing.T) {
+	clamd := scantest.NewClamd(t)
+	scanner := newClamdScanner(t, clamd.Address())
+
+	// Larger than a chunk, so the file is streamed in several.
+	clean := strings.Repeat("prescription ", clamdChunkSize/4)
+	result, err := scanner.Scan(context.Background(), strings.NewReader(clean))
+	if err != nil {
+		t.Fatalf("clean file: unexpected error: %v", err)
+	}
+	if result.Infected {
+		t.Errorf("clean file: flagged as %s", result.Signature)
+	}
+
+	result, err = scanner.Scan(context.Background(), strings.NewReader(clean+scantest.EICAR))
+	if err != nil {
+		t.Fatalf("EICAR: unexpected error: %v", err)
+	}
+	if !result.Infected || result.Signature != scantest.EICARSignature {
+		t.Errorf("EICAR: got %+v, want infected with %s", result, scantest.EICARSignature)
+	}
+
+	if got := clamd.Scanned(); got != 2 {
+		t.Errorf("clamd scanned %d streams, want 2", got)
+	}
+}
+
+func TestClamdScannerScanErrors(t *testing.T) {
+	clamd := scantest.NewClamd(t)
+	clamd.SetFailing(true)
+
+	_, err := newClamdScanner(t, clamd.Address()).Scan(context.Background(), strings.NewReader("data"))
+	if err == nil || !strings.Contains(err.Error(), "Can't allocate memory") {
+		t.Errorf("failing clamd: got error %v, want the clamd error", err)
+	}
+
+	_, err = newClamdScanner(t, unreachableAddress(t)).Scan(context.Background(), strings.NewReader("data"))
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to clamd") {
+		t.Errorf("clamd down: got error %v, want a connection error", err)
+	}
+}
+
+func TestClamdScannerPing(t *testing.T) {
+	clamd := scantest.NewClamd(t)
+	if err := newClamdScanner(t, clamd.Address()).Ping(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := newClamdScanner(t, unreachableAddress(t)).Ping(context.Background()); err == nil {
+		t.Error("clamd down: expected an error")
+	}
+}
+
+func TestNewClamdScanner(t *testing.T) {
+	for _, address := range []string{"localhost:3310", "http://localhost:3310", "tcp://[::1"} {
+		if _, err := NewClamdScanner(address, time.Second); err == nil {
+			t.Errorf("%s: expected an error", address)
+		}
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  Result
+		err   string
+	}{
+		{"stream: OK", Result{}, ""},
+		{"OK", Result{}, ""},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND", Result{Infected: true, Signature: "Win.Test.EICAR_HDB-1"}, ""},
+		{"stream: Can't allocate memory ERROR", Result{}, "clamd error: Can't allocate memory"},
+		{"INSTREAM size limit exceeded. ERROR", Result{}, "clamd error: INSTREAM size limit exceeded."},
+		{"UNKNOWN COMMAND", Result{}, "unexpected clamd reply"},
+		{"", Result{}, "unexpected clamd reply"},
+	}
+
+	for _, tt := range tests {
+		result, err := parseReply(tt.reply)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.reply, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%q: got error %v, want %q", tt.reply, err, tt.err)
+		case result != tt.want:
+			t.Errorf("%q: got %+v, want %+v", tt.reply, result, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package scan checks uploaded files for malware before they are stored, so
+// that admins and pharmacists never open a file that was flagged.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// Result is the verdict on a scanned file.
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// Scanner scans a file's content.
+type Scanner interface {
+	Scan(ctx context.Context, body io.Reader) (Result, error)
+}
+
+// NewScanner creates the scanner selected by the configuration.
+func NewScanner(cfg config.ScanConfig) (Scanner, error) {
+	switch cfg.Driver {
+	case "clamd":
+		return NewClamdScanner(cfg.ClamdAddress, cfg.Timeout)
+	case "", "none":
+		return NoopScanner{}, nil
+	default:
+		return nil, fmt.Errorf("unknown scan driver %q", cfg.Driver)
+	}
+}
+
+// NoopScanner passes every file.
+type NoopScanner struct{}
+
+func (NoopScanner) Scan(ctx context.Context, body io.Reader) (Result, error) {
+	return Result{}, nil
+}
+
+var (
+	// ErrInfected is returned by Guard.Check for flagged files.
+	ErrInfected = errors.New("file was flagged by the malware scanner")
+	// ErrUnavailable is returned by Guard.Check when the file could not be
+	// scanned and the guard fails closed.
+	ErrUnavailable = errors.New("malware scanner is unavailable")
+)
+
+// Guard scans uploads before they are stored and moves flagged files to a
+// quarantine store, where they are kept privately for inspection. When the
+// scanner cannot be reached it either refuses uploads (fail closed) or lets
+// them through unscanned (fail open).
+type Guard struct {
+	scanner    Scanner
+	quarantine storage.BlobStore
+	failOpen   bool
+}
+
+// NewGuard creates a guard. quarantine may be nil for a scanner that never
+// flags files, such as NoopScanner; flagged files are then only refused.
+func NewGuard(scanner Scanner, quarantine storage.BlobStore, failOpen bool) *Guard {
+	return &Guard{scanner: scanner, quarantine: quarantine, failOpen: failOpen}
+}
+
+// Check scans a file uploaded for purpose, e.g. "products".
+func (g *Guard) Check(ctx context.Context, purpose string, file *upload.File) error {
+	start := time.Now()
+	result, err := g.scanner.Scan(ctx, file.Reader())
+	if err != nil {
+		utils.UploadScansTotal.WithLabelValues(purpose, "error").Inc()
+		utils.UploadScanDuration.WithLabelValues(purpose).Observe(time.Since(start).Seconds())
+
+		if g.failOpen {
+			utils.WarnContext(ctx, "Storing upload without malware scan", map[string]interface{}{
+				"error":   err,
+				"purpose": purpose,
+			})
+			return nil
+		}
+		utils.ErrorContext(ctx, "Failed to scan upload", map[string]interface{}{
+			"error":   err,
+			"purpose": purpose,
+		})
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	utils.UploadScanDuration.WithLabelValues(purpose).Observe(time.Since(start).Seconds())
+
+	if !result.Infected {
+		utils.UploadScansTotal.WithLabelValues(purpose, "clean").Inc()
+		return nil
+	}
+	utils.UploadScansTotal.WithLabelValues(purpose, "infected").Inc()
+
+	if g.quarantine == nil {
+		utils.WarnContext(ctx, "Upload flagged by malware scanner", map[string]interface{}{
+			"purpose":   purpose,
+			"signature": result.Signature,
+		})
+		return fmt.Errorf("%w: %s", ErrInfected, result.Signature)
+	}
+
+	key, err := storage.Upload(ctx, g.quarantine, "quarantine/"+purpose, file, storage.PutOptions{
+		ContentType: "application/octet-stream",
+		Private:     true,
+	})
+	if err != nil {
+		utils.ErrorContext(ctx, "Failed to quarantine upload", map[string]interface{}{
+			"error":     err,
+			"purpose":   purpose,
+			"signature": result.Signature,
+		})
+	}
+	utils.WarnContext(ctx, "Upload flagged by malware scanner", map[string]interface{}{
+		"purpose":        purpose,
+		"signature":      result.Signature,
+		"quarantine_key": key,
+	})
+
+	return fmt.Errorf("%w: %s", ErrInfected, result.Signature)
+}
@@ -0,0 +1,119 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/internal/scan/scantest"
+	"github.com/PharmaKart/gateway-svc/internal/storage"
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+func init() {
+	utils.InitLogger(config.LoggingConfig{Level: "fatal"})
+}
+
+// fakeQuarantine records the objects put into it.
+type fakeQuarantine struct {
+	storage.BlobStore
+
+	objects map[string]string
+	options map[string]storage.PutOptions
+}
+
+func (f *fakeQuarantine) Put(ctx context.Context, key string, body io.Reader, opts storage.PutOptions) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	f.objects[key] = string(data)
+	f.options[key] = opts
+	return nil
+}
+
+func newGuard(t *testing.T, clamd *scantest.Clamd, failOpen bool) (*Guard, *fakeQuarantine) {
+	t.Helper()
+
+	quarantine := &fakeQuarantine{objects: map[string]string{}, options: map[string]storage.PutOptions{}}
+	return NewGuard(newClamdScanner(t, clamd.Address()), quarantine, failOpen), quarantine
+}
+
+func pdfFile(data string) *upload.File {
+	return &upload.File{Data: []byte(data), ContentType: upload.TypePDF, Ext: ".pdf"}
+}
+
+func TestGuardCheck(t *testing.T) {
+	clamd := scantest.NewClamd(t)
+	guard, quarantine := newGuard(t, clamd, false)
+
+	if err := guard.Check(context.Background(), "prescriptions", pdfFile("%PDF-1.7 clean")); err != nil {
+		t.Errorf("clean file: unexpected error: %v", err)
+	}
+	if len(quarantine.objects) != 0 {
+		t.Errorf("clean file: quarantined %v", quarantine.objects)
+	}
+
+	infected := "%PDF-1.7 " + scantest.EICAR
+	err := guard.Check(context.Background(), "prescriptions", pdfFile(infected))
+	if !errors.Is(err, ErrInfected) || !strings.Contains(err.Error(), scantest.EICARSignature) {
+		t.Errorf("EICAR: got error %v, want ErrInfected with the signature", err)
+	}
+	if len(quarantine.objects) != 1 {
+		t.Fatalf("EICAR: quarantined %d objects, want 1", len(quarantine.objects))
+	}
+	for key, data := range quarantine.objects {
+		if !strings.HasPrefix(key, "quarantine/prescriptions/") || data != infected {
+			t.Errorf("EICAR: quarantined %s with %q", key, data)
+		}
+		if !quarantine.options[key].Private {
+			t.Errorf("EICAR: quarantined %s publicly", key)
+		}
+	}
+}
+
+func TestGuardCheckScannerDown(t *testing.T) {
+	tests := []struct {
+		name     string
+		failOpen bool
+	}{
+		{name: "failing", failOpen: false},
+		{name: "failing", failOpen: true},
+		{name: "unreachable", failOpen: false},
+		{name: "unreachable", failOpen: true},
+	}
+
+	for _, tt := range tests {
+		clamd := scantest.NewClamd(t)
+		guard, quarantine := newGuard(t, clamd, tt.failOpen)
+		if tt.name == "failing" {
+			clamd.SetFailing(true)
+		} else {
+			guard.scanner = newClamdScanner(t, unreachableAddress(t))
+		}
+
+		err := guard.Check(context.Background(), "products", pdfFile(scantest.EICAR))
+		switch {
+		case tt.failOpen && err != nil:
+			t.Errorf("%s clamd, failing open: unexpected error: %v", tt.name, err)
+		case !tt.failOpen && !errors.Is(err, ErrUnavailable):
+			t.Errorf("%s clamd, failing closed: got error %v, want ErrUnavailable", tt.name, err)
+		}
+		if len(quarantine.objects) != 0 {
+			t.Errorf("%s clamd: quarantined %v", tt.name, quarantine.objects)
+		}
+	}
+}
+
+func TestGuardCheckWithoutQuarantine(t *testing.T) {
+	clamd := scantest.NewClamd(t)
+	guard := NewGuard(newClamdScanner(t, clamd.Address()), nil, false)
+
+	if err := guard.Check(context.Background(), "products", pdfFile(scantest.EICAR)); !errors.Is(err, ErrInfected) {
+		t.Errorf("got error %v, want ErrInfected", err)
+	}
+}
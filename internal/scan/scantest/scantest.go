@@ -0,0 +1,124 @@
+// Package scantest runs a fake clamd for tests of the upload path, so the
+// ClamAV driver can be exercised without a real daemon.
+//
+//	clamd := scantest.NewClamd(t)
+//	scanner, _ := scan.NewClamdScanner(clamd.Address(), time.Second)
+//
+// The fake flags any stream containing the EICAR test string.
+package scantest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// EICAR is the standard antivirus test file.
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// EICARSignature is the signature reported for EICAR.
+const EICARSignature = "Win.Test.EICAR_HDB-1"
+
+// Clamd is a fake clamd answering PING and INSTREAM over TCP.
+type Clamd struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	scanned int
+	fail    bool
+}
+
+// NewClamd starts a fake clamd that is stopped when the test ends.
+func NewClamd(t testing.TB) *Clamd {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake clamd: %v", err)
+	}
+
+	clamd := &Clamd{listener: listener}
+	go clamd.serve()
+	t.Cleanup(func() { listener.Close() })
+	return clamd
+}
+
+// Address is the clamd address to configure, e.g. for CLAMD_ADDRESS.
+func (c *Clamd) Address() string {
+	return "tcp://" + c.listener.Addr().String()
+}
+
+// Scanned is the number of streams scanned so far.
+func (c *Clamd) Scanned() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scanned
+}
+
+// SetFailing makes the fake answer every scan with an error, as clamd does
+// when it cannot scan, to test fail-open and fail-closed behaviour.
+func (c *Clamd) SetFailing(fail bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fail = fail
+}
+
+func (c *Clamd) serve() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		go c.handle(conn)
+	}
+}
+
+func (c *Clamd) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch command {
+	case "zPING\x00":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		var data bytes.Buffer
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(reader, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(&data, reader, int64(n)); err != nil {
+				return
+			}
+		}
+
+		c.mu.Lock()
+		c.scanned++
+		fail := c.fail
+		c.mu.Unlock()
+
+		switch {
+		case fail:
+			conn.Write([]byte("stream: Can't allocate memory ERROR\x00"))
+		case bytes.Contains(data.Bytes(), []byte(EICAR)):
+			conn.Write([]byte("stream: " + EICARSignature + " FOUND\x00"))
+		default:
+			conn.Write([]byte("stream: OK\x00"))
+		}
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
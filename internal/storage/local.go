@@ -0,0 +1,437 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// LocalStore keeps objects in a directory and serves them itself, for
+// development and integration tests without a cloud account. Public objects
+// are served as is; private ones only through presigned URLs, which are
+// signed with STORAGE_SIGNING_KEY. Presigned POST uploads are accepted the
+// same way S3 accepts them.
+type LocalStore struct {
+	root       string
+	prefix     string
+	baseURL    string
+	signingKey []byte
+}
+
+// localMetadata is kept next to every object, as objects/<key> and
+// meta/<key>.json.
+type localMetadata struct {
+	ContentType string `json:"content_type"`
+	Private     bool   `json:"private"`
+}
+
+// localPostPolicy is the signed "policy" field of a presigned POST.
+type localPostPolicy struct {
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	MaxSize     int64  `json:"max_size"`
+	Expires     int64  `json:"expires"`
+}
+
+var (
+	ephemeralKeyOnce sync.Once
+	ephemeralKey     []byte
+)
+
+func NewLocalStore(cfg config.StorageConfig, bucket string) (*LocalStore, error) {
+	if bucket == "" || !filepath.IsLocal(bucket) || strings.ContainsAny(bucket, `/\`) {
+		return nil, fmt.Errorf("invalid bucket name %q", bucket)
+	}
+
+	root := filepath.Join(cfg.LocalDir, bucket)
+	for _, dir := range []string{"objects", "meta"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	signingKey := []byte(cfg.SigningKey)
+	if len(signingKey) == 0 {
+		// Every store in the process shares the key, so stores in the same
+		// directory accept each other's URLs; they stop working on restart.
+		ephemeralKeyOnce.Do(func() {
+			ephemeralKey = make([]byte, 32)
+			if _, err := rand.Read(ephemeralKey); err != nil {
+				panic(err)
+			}
+			utils.Warn("STORAGE_SIGNING_KEY is not set; presigned URLs will not survive a restart", nil)
+		})
+		signingKey = ephemeralKey
+	}
+
+	prefix := "/blobs/" + bucket + "/"
+	return &LocalStore{
+		root:       root,
+		prefix:     prefix,
+		baseURL:    strings.TrimSuffix(cfg.LocalURL, "/") + prefix,
+		signingKey: signingKey,
+	}, nil
+}
+
+// Prefix is the path the gateway serves the store's objects under.
+func (s *LocalStore) Prefix() string {
+	return s.prefix
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	objectPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{objectPath, metaPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(localMetadata{ContentType: opts.ContentType, Private: opts.Private})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(metaPath, meta, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), objectPath)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (*Object, error) {
+	info, _, err := s.head(key)
+	if err != nil {
+		return nil, err
+	}
+
+	objectPath, _, _ := s.paths(key)
+	file, err := os.Open(objectPath)
+	if err != nil {
+		return nil, localError(err)
+	}
+	return &Object{ObjectInfo: *info, Body: file}, nil
+}
+
+func (s *LocalStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, _, err := s.head(key)
+	return info, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	objectPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{objectPath, metaPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) Copy(ctx context.Context, srcKey, dstKey string, opts PutOptions) error {
+	object, err := s.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	if opts.ContentType == "" {
+		opts.ContentType = object.ContentType
+	}
+	return s.Put(ctx, dstKey, object.Body, opts)
+}
+
+func (s *LocalStore) Presign(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, _, err := s.paths(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {s.sign(key, expires)},
+	}
+	return s.URL(key) + "?" + query.Encode(), nil
+}
+
+// PresignPost signs a policy that ServeHTTP checks when the form is posted
+// to the store's prefix. Server-side encryption is not supported.
+func (s *LocalStore) PresignPost(ctx context.Context, key string, conditions PostConditions, ttl time.Duration) (*PresignedPost, error) {
+	if _, _, err := s.paths(key); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	policy, err := json.Marshal(localPostPolicy{
+		Key:         key,
+		ContentType: conditions.ContentType,
+		MaxSize:     conditions.MaxSize,
+		Expires:     expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.RawURLEncoding.EncodeToString(policy)
+
+	return &PresignedPost{
+		URL: s.baseURL,
+		Fields: map[string]string{
+			"key":          key,
+			"Content-Type": conditions.ContentType,
+			"policy":       encodedPolicy,
+			"signature":    s.sign("post", encodedPolicy),
+		},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.baseURL + key
+}
+
+// ServeHTTP serves the object named by the request path below Prefix.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.servePost(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, s.prefix)
+	info, meta, err := s.head(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	presigned := r.URL.Query().Get("signature") != ""
+	if meta.Private || presigned {
+		if !s.verify(key, r.URL.Query().Get("expires"), r.URL.Query().Get("signature")) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Disposition", "inline")
+	}
+
+	objectPath, _, _ := s.paths(key)
+	file, err := os.Open(objectPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.LastModified, file)
+}
+
+// servePost stores a file posted with a presigned POST form. Like S3, the
+// fields must precede the file, and the object is stored privately.
+func (s *LocalStore) servePost(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "expected a multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	fields := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			http.Error(w, "missing file field", http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, 8*1024))
+			if err != nil {
+				http.Error(w, "invalid form", http.StatusBadRequest)
+				return
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		policy, ok := s.verifyPost(fields)
+		if !ok {
+			http.Error(w, "invalid or expired policy", http.StatusForbidden)
+			return
+		}
+
+		body := &postBody{r: part, max: policy.MaxSize}
+		err = s.Put(r.Context(), policy.Key, body, PutOptions{ContentType: policy.ContentType, Private: true})
+		switch {
+		case errors.Is(body.err, errPostSize):
+			http.Error(w, "file size is outside the allowed range", http.StatusBadRequest)
+			return
+		case body.err != nil:
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		case err != nil:
+			utils.ErrorContext(r.Context(), "Failed to store posted file", map[string]interface{}{
+				"error": err,
+				"key":   policy.Key,
+			})
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+}
+
+var errPostSize = errors.New("file size is outside the allowed range")
+
+// postBody streams the file of a presigned POST into the store, failing with
+// errPostSize once it exceeds max bytes or, at its end, if it is empty. The
+// failure is kept in err, so it can be told apart from storage errors.
+type postBody struct {
+	r   io.Reader
+	max int64
+	n   int64
+	err error
+}
+
+func (b *postBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	switch {
+	case b.n > b.max, err == io.EOF && b.n == 0:
+		b.err = errPostSize
+	case err != nil && err != io.EOF:
+		b.err = err
+	default:
+		return n, err
+	}
+	return n, b.err
+}
+
+// verifyPost checks the signed policy and that the form fields match it.
+func (s *LocalStore) verifyPost(fields map[string]string) (*localPostPolicy, bool) {
+	encodedPolicy := fields["policy"]
+	if !hmac.Equal([]byte(fields["signature"]), []byte(s.sign("post", encodedPolicy))) {
+		return nil, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encodedPolicy)
+	if err != nil {
+		return nil, false
+	}
+	var policy localPostPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, false
+	}
+
+	if time.Now().Unix() > policy.Expires || fields["key"] != policy.Key || fields["Content-Type"] != policy.ContentType {
+		return nil, false
+	}
+	return &policy, true
+}
+
+func (s *LocalStore) head(key string) (*ObjectInfo, *localMetadata, error) {
+	objectPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat, err := os.Stat(objectPath)
+	if err != nil {
+		return nil, nil, localError(err)
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	var meta localMetadata
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, localError(err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, nil, err
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  meta.ContentType,
+		LastModified: stat.ModTime(),
+	}, &meta, nil
+}
+
+// paths returns where the object and its metadata are kept, rejecting keys
+// that would escape the store's directory.
+func (s *LocalStore) paths(key string) (string, string, error) {
+	name := filepath.FromSlash(key)
+	if key == "" || strings.Contains(key, `\`) || !filepath.IsLocal(name) {
+		return "", "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.root, "objects", name), filepath.Join(s.root, "meta", name+".json"), nil
+}
+
+// sign covers the store's prefix, so a signature issued for one bucket is not
+// accepted by another in the same directory.
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(s.prefix + "\n" + key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStore) verify(key, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(key, expires)))
+}
+
+// localError maps missing files to ErrNotFound.
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+func newLocalStores(t *testing.T, buckets ...string) []*LocalStore {
+	t.Helper()
+
+	cfg := config.StorageConfig{LocalDir: t.TempDir(), SigningKey: "local-test"}
+	stores := make([]*LocalStore, len(buckets))
+	for i, bucket := range buckets {
+		store, err := NewLocalStore(cfg, bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[i] = store
+	}
+	return stores
+}
+
+// get requests rawURL, one of the store's URLs, from the store.
+func get(store *LocalStore, rawURL string) int {
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, rawURL, nil))
+	return rec.Code
+}
+
+func TestLocalStorePaths(t *testing.T) {
+	store := newLocalStores(t, "images")[0]
+
+	for _, key := range []string{"products/a.png", "a.png", "uploads/confirmed/products/a.png"} {
+		if _, _, err := store.paths(key); err != nil {
+			t.Errorf("%q: unexpected error: %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "../a.png", "products/../../a.png", "/etc/passwd", `products\..\..\a.png`, ".."} {
+		if _, _, err := store.paths(key); err == nil {
+			t.Errorf("%q: expected an error", key)
+		}
+	}
+}
+
+func TestLocalStorePresign(t *testing.T) {
+	stores := newLocalStores(t, "images", "prescriptions")
+	images, prescriptions := stores[0], stores[1]
+	ctx := context.Background()
+
+	for _, store := range stores {
+		if err := store.Put(ctx, "a.pdf", strings.NewReader("%PDF-1.7"), PutOptions{ContentType: "application/pdf", Private: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	valid, _ := prescriptions.Presign(ctx, "a.pdf", time.Minute)
+	if status := get(prescriptions, valid); status != http.StatusOK {
+		t.Errorf("presigned: got status %d, want 200", status)
+	}
+	if status := get(prescriptions, prescriptions.URL("a.pdf")); status != http.StatusForbidden {
+		t.Errorf("unsigned: got status %d, want 403", status)
+	}
+
+	expired, _ := prescriptions.Presign(ctx, "a.pdf", -time.Second)
+	if status := get(prescriptions, expired); status != http.StatusForbidden {
+		t.Errorf("expired: got status %d, want 403", status)
+	}
+
+	// A signature for the same key in another bucket
+	other, _ := images.Presign(ctx, "a.pdf", time.Minute)
+	query := other[strings.Index(other, "?"):]
+	if status := get(prescriptions, prescriptions.URL("a.pdf")+query); status != http.StatusForbidden {
+		t.Errorf("other bucket: got status %d, want 403", status)
+	}
+}
+
+// postForm builds the multipart form of a presigned POST, with the file last.
+func postForm(fields map[string]string, file []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	part, _ := form.CreateFormFile("file", "upload")
+	part.Write(file)
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestLocalStorePresignPost(t *testing.T) {
+	stores := newLocalStores(t, "images", "prescriptions")
+	images, prescriptions := stores[0], stores[1]
+	ctx := context.Background()
+	conditions := PostConditions{ContentType: "application/pdf", MaxSize: 16}
+
+	post, err := prescriptions.PresignPost(ctx, "uploads/a.pdf", conditions, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _ := prescriptions.PresignPost(ctx, "uploads/a.pdf", conditions, -time.Second)
+	otherBucket, _ := images.PresignPost(ctx, "uploads/a.pdf", conditions, time.Minute)
+
+	with := func(fields map[string]string, name, value string) map[string]string {
+		changed := make(map[string]string, len(fields))
+		for k, v := range fields {
+			changed[k] = v
+		}
+		changed[name] = value
+		return changed
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		file   string
+		want   int
+	}{
+		{"too large", post.Fields, strings.Repeat("x", 17), http.StatusBadRequest},
+		{"empty", post.Fields, "", http.StatusBadRequest},
+		{"expired", expired.Fields, "%PDF-1.7", http.StatusForbidden},
+		{"other bucket", otherBucket.Fields, "%PDF-1.7", http.StatusForbidden},
+		{"other key", with(post.Fields, "key", "uploads/b.pdf"), "%PDF-1.7", http.StatusForbidden},
+		{"other content type", with(post.Fields, "Content-Type", "text/html"), "%PDF-1.7", http.StatusForbidden},
+		{"tampered policy", with(post.Fields, "policy", post.Fields["policy"]+"x"), "%PDF-1.7", http.StatusForbidden},
+		{"valid", post.Fields, "%PDF-1.7", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		body, contentType := postForm(tt.fields, []byte(tt.file))
+		req := httptest.NewRequest(http.MethodPost, mustPath(t, post.URL), body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		prescriptions.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+		_, err := prescriptions.Head(ctx, "uploads/a.pdf")
+		if stored := err == nil; stored != (tt.want == http.StatusNoContent) {
+			t.Errorf("%s: stored=%t, err=%v", tt.name, stored, err)
+		}
+	}
+
+	info, _, err := prescriptions.head("uploads/a.pdf")
+	if err != nil || info.ContentType != "application/pdf" || info.Size != int64(len("%PDF-1.7")) {
+		t.Errorf("stored %+v, %v", info, err)
+	}
+	if status := get(prescriptions, prescriptions.URL("uploads/a.pdf")); status != http.StatusForbidden {
+		t.Errorf("posted object is public: got status %d, want 403", status)
+	}
+	if _, err := images.Head(ctx, "uploads/a.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("posted object reached the other bucket: %v", err)
+	}
+}
+
+func mustPath(t *testing.T, rawURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Path
+}
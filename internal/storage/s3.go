@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Store keeps objects in an S3 bucket. With an endpoint it talks to any
+// S3-compatible service such as MinIO, usually with path-style addressing.
+type S3Store struct {
+	client    *s3.S3
+	uploader  *s3manager.Uploader
+	bucket    string
+	region    string
+	bucketURL string
+	baseURL   string
+}
+
+func NewS3Store(cfg config.StorageConfig, bucket string) (*S3Store, error) {
+	awsCfg := &aws.Config{
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.PathStyle),
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	if cfg.AccessKeyID != "" {
+		awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 session: %w", err)
+	}
+
+	bucketURL, err := s3BucketURL(cfg, bucket)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := bucketURL
+	if cfg.PublicURL != "" {
+		baseURL = strings.TrimSuffix(cfg.PublicURL, "/") + "/"
+	}
+
+	client := s3.New(sess)
+	return &S3Store{
+		client:    client,
+		uploader:  s3manager.NewUploaderWithClient(client),
+		bucket:    bucket,
+		region:    cfg.Region,
+		bucketURL: bucketURL,
+		baseURL:   baseURL,
+	}, nil
+}
+
+// s3BucketURL returns the address of bucket on the storage service.
+func s3BucketURL(cfg config.StorageConfig, bucket string) (string, error) {
+	if cfg.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", bucket, cfg.Region), nil
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return "", fmt.Errorf("invalid storage endpoint %q", cfg.Endpoint)
+	}
+	if cfg.PathStyle {
+		return fmt.Sprintf("%s://%s/%s/", endpoint.Scheme, endpoint.Host, bucket), nil
+	}
+	return fmt.Sprintf("%s://%s.%s/", endpoint.Scheme, bucket, endpoint.Host), nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.Private {
+		input.ACL = aws.String(s3.ObjectCannedACLPrivate)
+	}
+	if opts.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(opts.ServerSideEncryption)
+	}
+	if opts.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(opts.KMSKeyID)
+	}
+
+	_, err := s.uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (*Object, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+
+	return &Object{
+		ObjectInfo: ObjectInfo{
+			Key:          key,
+			Size:         aws.Int64Value(out.ContentLength),
+			ContentType:  aws.StringValue(out.ContentType),
+			LastModified: aws.TimeValue(out.LastModified),
+		},
+		Body: out.Body,
+	}, nil
+}
+
+func (s *S3Store) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	out, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.Int64Value(out.ContentLength),
+		ContentType:  aws.StringValue(out.ContentType),
+		LastModified: aws.TimeValue(out.LastModified),
+	}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return s3Error(err)
+}
+
+func (s *S3Store) Copy(ctx context.Context, srcKey, dstKey string, opts PutOptions) error {
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(url.PathEscape(s.bucket + "/" + srcKey)),
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+		input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+	}
+	// Neither the ACL nor the encryption of the source carries over.
+	if opts.Private {
+		input.ACL = aws.String(s3.ObjectCannedACLPrivate)
+	}
+	if opts.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(opts.ServerSideEncryption)
+	}
+	if opts.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(opts.KMSKeyID)
+	}
+
+	_, err := s.client.CopyObjectWithContext(ctx, input)
+	return s3Error(err)
+}
+
+func (s *S3Store) Presign(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket:                     aws.String(s.bucket),
+		Key:                        aws.String(key),
+		ResponseCacheControl:       aws.String("no-store"),
+		ResponseContentDisposition: aws.String("inline"),
+	})
+	req.SetContext(ctx)
+	return req.Presign(ttl)
+}
+
+// PresignPost signs an S3 POST policy (Signature Version 4) for the upload.
+func (s *S3Store) PresignPost(ctx context.Context, key string, conditions PostConditions, ttl time.Duration) (*PresignedPost, error) {
+	creds, err := s.client.Config.Credentials.GetWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage credentials: %w", err)
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+	date := now.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+
+	fields := map[string]string{
+		"key":              key,
+		"Content-Type":     conditions.ContentType,
+		"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+		"x-amz-credential": creds.AccessKeyID + "/" + scope,
+		"x-amz-date":       now.Format("20060102T150405Z"),
+	}
+	if creds.SessionToken != "" {
+		fields["x-amz-security-token"] = creds.SessionToken
+	}
+	if conditions.ServerSideEncryption != "" {
+		fields["x-amz-server-side-encryption"] = conditions.ServerSideEncryption
+	}
+	if conditions.KMSKeyID != "" {
+		fields["x-amz-server-side-encryption-aws-kms-key-id"] = conditions.KMSKeyID
+	}
+
+	policyConditions := []interface{}{
+		map[string]string{"bucket": s.bucket},
+		[]interface{}{"content-length-range", 1, conditions.MaxSize},
+	}
+	for name, value := range fields {
+		policyConditions = append(policyConditions, map[string]string{name: value})
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": expiresAt.Format("2006-01-02T15:04:05.000Z"),
+		"conditions": policyConditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policy)
+
+	signingKey := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+
+	fields["policy"] = encodedPolicy
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signingKey, encodedPolicy))
+
+	return &PresignedPost{URL: s.bucketURL, Fields: fields, ExpiresAt: expiresAt}, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func (s *S3Store) URL(key string) string {
+	return s.baseURL + key
+}
+
+// s3Error maps missing objects to ErrNotFound.
+func s3Error(err error) error {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
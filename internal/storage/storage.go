@@ -0,0 +1,184 @@
+// Package storage keeps uploaded files such as product images and
+// prescriptions in a blob store: AWS S3, any S3-compatible service, or a
+// directory on the gateway's own disk.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/internal/upload"
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// PutOptions describe a stored object. Private objects are only readable
+// through presigned URLs; public ones through URL.
+type PutOptions struct {
+	ContentType          string
+	Private              bool
+	ServerSideEncryption string
+	KMSKeyID             string
+}
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Object is an open stored object. Body must be closed.
+type Object struct {
+	ObjectInfo
+	Body io.ReadCloser
+}
+
+// BlobStore stores objects by key in a single bucket.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, opts PutOptions) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	// Copy copies the object at srcKey to dstKey within the store, without
+	// passing it through the gateway. The copy keeps the content type unless
+	// opts sets one.
+	Copy(ctx context.Context, srcKey, dstKey string, opts PutOptions) error
+	// Presign returns a URL that reads the object until ttl has passed, even
+	// when it is private. Responses to it are not cached.
+	Presign(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// PresignPost returns a form a client can POST a file to, storing it
+	// privately as key until ttl has passed, as long as it meets the
+	// conditions.
+	PresignPost(ctx context.Context, key string, conditions PostConditions, ttl time.Duration) (*PresignedPost, error)
+	// URL returns the permanent address of a public object.
+	URL(key string) string
+}
+
+// PostConditions restrict a file uploaded through a presigned POST.
+type PostConditions struct {
+	ContentType          string
+	MaxSize              int64
+	ServerSideEncryption string
+	KMSKeyID             string
+}
+
+// PresignedPost is a form for uploading a file directly to a store. The
+// client sends Fields, followed by the file in a field named "file", as
+// multipart/form-data to URL.
+type PresignedPost struct {
+	URL       string
+	Fields    map[string]string
+	ExpiresAt time.Time
+}
+
+// Stores are the blob stores the gateway writes uploads to.
+type Stores struct {
+	Images        BlobStore
+	Prescriptions BlobStore
+	Quarantine    BlobStore
+}
+
+// NewStores creates a store for product images in cfg.S3Bucket, one for
+// prescriptions in cfg.Prescriptions.Bucket and one for files flagged by the
+// malware scanner in cfg.Scan.QuarantineBucket, using the configured driver.
+// Product images are public, so prescriptions must not share their bucket:
+// a private object ACL does not override a bucket policy granting read.
+// Flagged files are kept apart from both. Without a scanner that can flag
+// files the quarantine bucket is optional and Quarantine may be nil.
+func NewStores(cfg *config.Config) (*Stores, error) {
+	switch cfg.Prescriptions.Bucket {
+	case "":
+		return nil, errors.New("PRESCRIPTION_BUCKET_NAME is required")
+	case cfg.S3Bucket:
+		return nil, errors.New("PRESCRIPTION_BUCKET_NAME must differ from S3_BUCKET_NAME, whose objects are public")
+	}
+	switch cfg.Scan.QuarantineBucket {
+	case "":
+		if cfg.Scan.Driver == "clamd" {
+			return nil, errors.New("SCAN_DRIVER=clamd requires SCAN_QUARANTINE_BUCKET")
+		}
+	case cfg.S3Bucket, cfg.Prescriptions.Bucket:
+		return nil, errors.New("SCAN_QUARANTINE_BUCKET must differ from S3_BUCKET_NAME and PRESCRIPTION_BUCKET_NAME")
+	}
+
+	images, err := New(cfg.Storage, cfg.S3Bucket)
+	if err != nil {
+		return nil, err
+	}
+	prescriptions, err := New(cfg.Storage, cfg.Prescriptions.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	stores := &Stores{Images: images, Prescriptions: prescriptions}
+
+	if cfg.Scan.QuarantineBucket != "" {
+		stores.Quarantine, err = New(cfg.Storage, cfg.Scan.QuarantineBucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stores, nil
+}
+
+// New creates the store for bucket selected by the configuration.
+func New(cfg config.StorageConfig, bucket string) (BlobStore, error) {
+	switch cfg.Driver {
+	case "", "s3":
+		return NewS3Store(cfg, bucket)
+	case "s3compatible":
+		if cfg.Endpoint == "" {
+			return nil, errors.New("the s3compatible storage driver requires STORAGE_ENDPOINT")
+		}
+		return NewS3Store(cfg, bucket)
+	case "local":
+		return NewLocalStore(cfg, bucket)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// Upload stores a validated file under folder with a generated name and
+// returns its key. The content type defaults to the detected one.
+func Upload(ctx context.Context, store BlobStore, folder string, file *upload.File, opts PutOptions) (string, error) {
+	key := fmt.Sprintf("%s/%s%s", folder, utils.NewRequestID(), file.Ext)
+	if opts.ContentType == "" {
+		opts.ContentType = file.ContentType
+	}
+
+	start := time.Now()
+	if err := store.Put(ctx, key, file.Reader(), opts); err != nil {
+		utils.S3UploadDuration.WithLabelValues(folder, "error").Observe(time.Since(start).Seconds())
+		return "", err
+	}
+	utils.S3UploadDuration.WithLabelValues(folder, "success").Observe(time.Since(start).Seconds())
+	utils.S3UploadSize.WithLabelValues(folder).Observe(float64(file.Size()))
+
+	utils.InfoContext(ctx, "Uploaded file", map[string]interface{}{
+		"key": key,
+	})
+
+	return key, nil
+}
+
+// KeyFromURL returns the key of the object at rawURL, if it is one of the
+// store's public URLs.
+func KeyFromURL(store BlobStore, rawURL string) (string, bool) {
+	base := store.URL("")
+	if !strings.HasPrefix(rawURL, base) {
+		return "", false
+	}
+	key := strings.TrimPrefix(rawURL, base)
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	return key, key != ""
+}
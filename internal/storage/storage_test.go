@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+func TestNewStoresBuckets(t *testing.T) {
+	tests := []struct {
+		name          string
+		prescriptions string
+		scanDriver    string
+		quarantine    string
+		err           string
+	}{
+		{"separate buckets", "prescriptions", "clamd", "quarantine", ""},
+		{"no scanner without quarantine", "prescriptions", "none", "", ""},
+		{"missing prescriptions", "", "none", "", "PRESCRIPTION_BUCKET_NAME is required"},
+		{"shared prescriptions", "images", "none", "", "must differ from S3_BUCKET_NAME"},
+		{"clamd without quarantine", "prescriptions", "clamd", "", "requires SCAN_QUARANTINE_BUCKET"},
+		{"quarantine in images", "prescriptions", "clamd", "images", "SCAN_QUARANTINE_BUCKET must differ"},
+		{"quarantine in prescriptions", "prescriptions", "clamd", "prescriptions", "SCAN_QUARANTINE_BUCKET must differ"},
+	}
+
+	for _, tt := range tests {
+		cfg := &config.Config{
+			S3Bucket:      "images",
+			Storage:       config.StorageConfig{Driver: "local", LocalDir: t.TempDir(), SigningKey: "storage-test"},
+			Prescriptions: config.PrescriptionStorageConfig{Bucket: tt.prescriptions},
+			Scan:          config.ScanConfig{Driver: tt.scanDriver, QuarantineBucket: tt.quarantine},
+		}
+
+		stores, err := NewStores(cfg)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		case err == nil && (stores.Quarantine != nil) != (tt.quarantine != ""):
+			t.Errorf("%s: got quarantine store %v", tt.name, stores.Quarantine)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package upload
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// JPEG markers.
+const (
+	jpegSOI  = 0xD8
+	jpegEOI  = 0xD9
+	jpegSOS  = 0xDA
+	jpegAPP0 = 0xE0
+	jpegAPP2 = 0xE2
+	jpegAPPE = 0xEE
+	jpegAPPF = 0xEF
+	jpegCOM  = 0xFE
+)
+
+// keepJPEGSegment reports whether a segment is needed to display the image.
+// APP0 (JFIF), APP2 (ICC colour profile) and APP14 (Adobe colour transform)
+// are kept; the other APPn segments, which hold EXIF, GPS, XMP and IPTC
+// metadata, and comments are dropped.
+func keepJPEGSegment(marker byte) bool {
+	if marker == jpegCOM {
+		return false
+	}
+	if marker >= jpegAPP0 && marker <= jpegAPPF {
+		return marker == jpegAPP0 || marker == jpegAPP2 || marker == jpegAPPE
+	}
+	return true
+}
+
+// sanitizeJPEG copies the image segment by segment, leaving out metadata.
+func sanitizeJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, jpegSOI)
+
+	pos := 2
+	for {
+		if pos >= len(data) || data[pos] != 0xFF {
+			return nil, errors.New("missing segment marker")
+		}
+		// Markers may be preceded by any number of fill bytes.
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, errors.New("truncated file")
+		}
+		marker := data[pos]
+		pos++
+
+		if marker == jpegEOI {
+			out = append(out, 0xFF, jpegEOI)
+			if err := checkTrailing(data[pos:]); err != nil {
+				return nil, err
+			}
+			return out, nil
+		}
+		if marker == jpegSOI || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			return nil, errors.New("unexpected marker")
+		}
+
+		if pos+2 > len(data) {
+			return nil, errors.New("truncated file")
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, errors.New("invalid segment length")
+		}
+		segment := data[pos : pos+length]
+		pos += length
+
+		// Application segments and comments are not image data, so even
+		// the kept ones are checked for other formats.
+		if marker == jpegCOM || (marker >= jpegAPP0 && marker <= jpegAPPF) {
+			if err := checkForeign(segment); err != nil {
+				return nil, err
+			}
+		}
+		if !keepJPEGSegment(marker) {
+			continue
+		}
+		out = append(out, 0xFF, marker)
+		out = append(out, segment...)
+
+		if marker == jpegSOS {
+			// Compressed data runs until the next marker other than a
+			// stuffed 0xFF00 or a restart marker.
+			start := pos
+			for {
+				if pos+1 >= len(data) {
+					return nil, errors.New("truncated image data")
+				}
+				if data[pos] == 0xFF {
+					next := data[pos+1]
+					if next != 0x00 && next != 0xFF && !(next >= 0xD0 && next <= 0xD7) {
+						break
+					}
+				}
+				pos++
+			}
+			out = append(out, data[start:pos]...)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+// testJPEG encodes a small image. The encoder writes no APPn segments, so
+// segments inserted after SOI are the only ones.
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 200, A: 255})
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// jpegSegment returns a segment with the given marker and payload.
+func jpegSegment(marker byte, payload string) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withJPEGSegments inserts segments after the SOI marker.
+func withJPEGSegments(data []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+func TestSanitizeJPEG(t *testing.T) {
+	clean := testJPEG(t)
+	exif := jpegSegment(0xE1, "Exif\x00\x00MM\x00\x2A GPSLatitude 43.6532")
+	jfif := jpegSegment(jpegAPP0, "JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00")
+	icc := jpegSegment(jpegAPP2, "ICC_PROFILE\x00\x01\x01 profile")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		dropped  []string
+		kept     []string
+		wantSize int
+	}{
+		{name: "clean", data: clean, wantSize: len(clean)},
+		{name: "EXIF", data: withJPEGSegments(clean, exif), dropped: []string{"Exif", "GPSLatitude"}, wantSize: len(clean)},
+		{name: "comment", data: withJPEGSegments(clean, jpegSegment(jpegCOM, "taken at home")), dropped: []string{"taken at home"}, wantSize: len(clean)},
+		{name: "JFIF and ICC", data: withJPEGSegments(clean, jfif, exif, icc), dropped: []string{"Exif"}, kept: []string{"JFIF", "ICC_PROFILE"}, wantSize: len(clean) + len(jfif) + len(icc)},
+		{name: "ZIP after EOI", data: append(append([]byte{}, clean...), "PK\x03\x04payload"...), want: "after its end"},
+		{name: "zero padding after EOI", data: append(append([]byte{}, clean...), 0, 0, 0), wantSize: len(clean)},
+		{name: "script in EXIF", data: withJPEGSegments(clean, jpegSegment(0xE1, "Exif\x00\x00<script>alert(1)</script>")), want: "embedded script"},
+		{name: "ZIP in ICC profile", data: withJPEGSegments(clean, jpegSegment(jpegAPP2, "ICC_PROFILE\x00PK\x03\x04")), want: "embedded ZIP"},
+		{name: "HTML in Adobe segment", data: withJPEGSegments(clean, jpegSegment(jpegAPPE, "Adobe<html>")), want: "embedded HTML"},
+		{name: "truncated", data: clean[:len(clean)-2], want: "truncated image data"},
+		{name: "bad segment length", data: withJPEGSegments(clean, []byte{0xFF, 0xE1, 0xFF, 0xFF}), want: "invalid segment length"},
+	}
+
+	for _, tt := range tests {
+		out, err := sanitizeJPEG(tt.data)
+		if tt.want != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		if len(out) != tt.wantSize {
+			t.Errorf("%s: got %d bytes, want %d", tt.name, len(out), tt.wantSize)
+		}
+		for _, s := range tt.dropped {
+			if bytes.Contains(out, []byte(s)) {
+				t.Errorf("%s: %q was kept", tt.name, s)
+			}
+		}
+		for _, s := range tt.kept {
+			if !bytes.Contains(out, []byte(s)) {
+				t.Errorf("%s: %q was dropped", tt.name, s)
+			}
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+			t.Errorf("%s: sanitized image does not decode: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package upload
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// pdfActiveContent are the PDF names of scripts, launched programs and
+// embedded files, which a prescription scan never needs.
+var pdfActiveContent = map[string]bool{
+	"JavaScript":   true,
+	"JS":           true,
+	"Launch":       true,
+	"EmbeddedFile": true,
+	"RichMedia":    true,
+}
+
+// maxPDFInflatedSize bounds the decompressed size of a document's object
+// streams, so a small upload cannot expand without limit while it is checked.
+const maxPDFInflatedSize = 32 << 20
+
+// sanitizePDF checks a PDF document for active content, including names
+// written with #xx escapes and objects packed into compressed object
+// streams. Documents are stored unchanged.
+func sanitizePDF(data []byte) ([]byte, error) {
+	eof := bytes.LastIndex(data, []byte("%%EOF"))
+	if eof < 0 {
+		return nil, errors.New("missing %%EOF marker")
+	}
+	// Writers may end the file with line breaks or padding.
+	if err := checkTrailing(bytes.TrimSpace(data[eof+len("%%EOF"):])); err != nil {
+		return nil, err
+	}
+
+	names := pdfNames(data)
+	if names["Encrypt"] {
+		return nil, reject("Encrypted documents cannot be checked")
+	}
+	if err := checkPDFNames(names); err != nil {
+		return nil, err
+	}
+
+	// Objects inside object streams are only visible once inflated.
+	budget := int64(maxPDFInflatedSize)
+	for _, stream := range pdfStreams(data) {
+		dict := pdfNames(stream.dict)
+		if !dict["ObjStm"] {
+			continue
+		}
+
+		objects, err := inflatePDFStream(stream, dict, &budget)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPDFNames(pdfNames(objects)); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func checkPDFNames(names map[string]bool) error {
+	for name := range pdfActiveContent {
+		if names[name] {
+			return reject("File contains active content (/%s)", name)
+		}
+	}
+	return nil
+}
+
+// pdfNames returns the names in data, with #xx escapes decoded, so that
+// /J#61vaScript is found as /JavaScript.
+func pdfNames(data []byte) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < len(data); i++ {
+		if data[i] != '/' {
+			continue
+		}
+
+		start := i + 1
+		end := start
+		for end < len(data) && isPDFRegular(data[end]) {
+			end++
+		}
+		names[decodePDFName(data[start:end])] = true
+		i = end - 1
+	}
+	return names
+}
+
+func decodePDFName(raw []byte) string {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw)
+	}
+
+	name := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			var decoded [1]byte
+			if _, err := hex.Decode(decoded[:], raw[i+1:i+3]); err == nil {
+				name = append(name, decoded[0])
+				i += 2
+				continue
+			}
+		}
+		name = append(name, raw[i])
+	}
+	return string(name)
+}
+
+// isPDFRegular reports whether c continues a PDF name, i.e. is neither
+// whitespace nor a delimiter.
+func isPDFRegular(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ', '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// pdfStream is a stream object: its dictionary and its encoded data.
+type pdfStream struct {
+	dict []byte
+	data []byte
+}
+
+// pdfStreams finds the stream objects of a document by their keywords. The
+// dictionary is what follows the last "obj" before "stream", and the data
+// runs up to "endstream", which also copes with indirect /Length values.
+func pdfStreams(data []byte) []pdfStream {
+	var streams []pdfStream
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], []byte("stream"))
+		if i < 0 {
+			return streams
+		}
+		keyword := offset + i
+		offset = keyword + len("stream")
+
+		// Skip "endstream" and words merely ending in "stream".
+		if keyword > 0 && isPDFRegular(data[keyword-1]) {
+			continue
+		}
+
+		start := offset
+		if bytes.HasPrefix(data[start:], []byte("\r\n")) {
+			start += 2
+		} else if start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+			start++
+		} else {
+			continue
+		}
+
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			return streams
+		}
+
+		dictStart := bytes.LastIndex(data[:keyword], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		streams = append(streams, pdfStream{
+			dict: data[dictStart:keyword],
+			data: data[start : start+end],
+		})
+		offset = start + end + len("endstream")
+	}
+}
+
+// inflatePDFStream decodes an object stream, which must be uncompressed or
+// compressed with FlateDecode alone. budget is the number of inflated bytes
+// the rest of the document may still use.
+func inflatePDFStream(stream pdfStream, dict map[string]bool, budget *int64) ([]byte, error) {
+	var filters []string
+	for name := range dict {
+		if pdfFilters[name] {
+			filters = append(filters, name)
+		}
+	}
+
+	switch {
+	case len(filters) == 0:
+		return stream.data, nil
+	case len(filters) > 1 || (filters[0] != "FlateDecode" && filters[0] != "Fl"):
+		return nil, reject("Compressed objects use an unsupported filter (/%s)", filters[0])
+	case dict["DecodeParms"] || dict["DP"]:
+		return nil, reject("Compressed objects use unsupported decode parameters")
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(stream.data))
+	if err != nil {
+		return nil, reject("Compressed objects cannot be read")
+	}
+	defer r.Close()
+
+	objects, err := io.ReadAll(io.LimitReader(r, *budget+1))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, reject("Compressed objects cannot be read")
+	}
+	if int64(len(objects)) > *budget {
+		return nil, reject("Compressed objects are too large")
+	}
+	*budget -= int64(len(objects))
+	return objects, nil
+}
+
+// pdfFilters are the standard stream filter names, with their abbreviations.
+var pdfFilters = map[string]bool{
+	"ASCIIHexDecode": true, "AHx": true,
+	"ASCII85Decode": true, "A85": true,
+	"LZWDecode": true, "LZW": true,
+	"FlateDecode": true, "Fl": true,
+	"RunLengthDecode": true, "RL": true,
+	"CCITTFaxDecode": true, "CCF": true,
+	"JBIG2Decode": true,
+	"DCTDecode":   true, "DCT": true,
+	"JPXDecode": true,
+	"Crypt":     true,
+}
@@ -0,0 +1,61 @@
+package upload
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+)
+
+func deflate(t *testing.T, data string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func pdfDocument(objects ...string) []byte {
+	return []byte("%PDF-1.7\n" + strings.Join(objects, "\n") + "\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+}
+
+func TestSanitizePDF(t *testing.T) {
+	catalog := "1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj"
+	action := "3 0 obj\n<< /S /JavaScript /JS (app.alert(1)) >>\nendobj"
+	objStm := func(dict, data string) string {
+		return "4 0 obj\n<< /Type /ObjStm /N 1 /First 4 " + dict + " >>\nstream\n" + data + "\nendstream\nendobj"
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"plain", pdfDocument(catalog), ""},
+		{"script", pdfDocument(catalog, action), "active content"},
+		{"escaped name", pdfDocument(catalog, "3 0 obj\n<< /S /J#61vaScript >>\nendobj"), "active content (/JavaScript)"},
+		{"escaped launch", pdfDocument(catalog, "3 0 obj\n<< /S /#4caunch >>\nendobj"), "active content (/Launch)"},
+		{"plain object stream", pdfDocument(catalog, objStm("/Filter /FlateDecode", deflate(t, "5 0 << /Type /Page >>"))), ""},
+		{"compressed script", pdfDocument(catalog, objStm("/Filter /FlateDecode", deflate(t, "5 0 << /S /Launch /F (calc.exe) >>"))), "active content (/Launch)"},
+		{"compressed escaped script", pdfDocument(catalog, objStm("/Filter [/Fl#61teDecode]", deflate(t, "5 0 << /JS (x) >>"))), "active content (/JS)"},
+		{"unsupported filter", pdfDocument(catalog, objStm("/Filter /LZWDecode", "xyz")), "unsupported filter"},
+		{"corrupt object stream", pdfDocument(catalog, objStm("/Filter /FlateDecode", "not deflate")), "cannot be read"},
+		{"encrypted", []byte("%PDF-1.7\ntrailer\n<< /Encrypt 9 0 R >>\n%%EOF"), "Encrypted"},
+	}
+
+	for _, tt := range tests {
+		_, err := sanitizePDF(tt.data)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package upload
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+// pngMetadataChunks hold EXIF data, text and timestamps, none of which is
+// needed to display the image.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// sanitizePNG copies the image chunk by chunk, leaving out metadata.
+func sanitizePNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	pos := len(pngSignature)
+	first := true
+	for {
+		if pos+8 > len(data) {
+			return nil, errors.New("truncated file")
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 8 + length + 4
+		if end > len(data) {
+			return nil, errors.New("invalid chunk length")
+		}
+		chunk := data[pos:end]
+		body := chunk[8 : 8+length]
+		if crc32.ChecksumIEEE(chunk[4:8+length]) != binary.BigEndian.Uint32(chunk[8+length:]) {
+			return nil, errors.New("corrupt chunk " + chunkType)
+		}
+		pos = end
+
+		if first && chunkType != "IHDR" {
+			return nil, errors.New("missing IHDR chunk")
+		}
+		first = false
+
+		if pngMetadataChunks[chunkType] {
+			if err := checkForeign(body); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		out = append(out, chunk...)
+
+		if chunkType == "IEND" {
+			if err := checkTrailing(data[pos:]); err != nil {
+				return nil, err
+			}
+			return out, nil
+		}
+	}
+}
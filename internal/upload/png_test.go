@@ -0,0 +1,98 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// testPNG encodes a small image, whose chunks are IHDR, IDAT and IEND.
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{G: 200, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngChunk returns a chunk of the given type with a valid CRC.
+func pngChunk(chunkType, body string) []byte {
+	chunk := make([]byte, 4, 12+len(body))
+	binary.BigEndian.PutUint32(chunk, uint32(len(body)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, body...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// withPNGChunks inserts chunks after IHDR, which ends 33 bytes into the file.
+func withPNGChunks(data []byte, chunks ...[]byte) []byte {
+	const ihdrEnd = 8 + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	for _, chunk := range chunks {
+		out = append(out, chunk...)
+	}
+	return append(out, data[ihdrEnd:]...)
+}
+
+func TestSanitizePNG(t *testing.T) {
+	clean := testPNG(t)
+	physical := pngChunk("pHYs", "\x00\x00\x0b\x13\x00\x00\x0b\x13\x01")
+
+	corrupt := pngChunk("tEXt", "Comment\x00hello")
+	corrupt[len(corrupt)-1] ^= 0xFF
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		dropped  []string
+		wantSize int
+	}{
+		{name: "clean", data: clean, wantSize: len(clean)},
+		{name: "text", data: withPNGChunks(clean, pngChunk("tEXt", "Author\x00Jane Doe")), dropped: []string{"Jane Doe"}, wantSize: len(clean)},
+		{name: "EXIF", data: withPNGChunks(clean, pngChunk("eXIf", "MM\x00\x2A GPSLatitude 43.6532")), dropped: []string{"GPSLatitude"}, wantSize: len(clean)},
+		{name: "timestamp", data: withPNGChunks(clean, pngChunk("tIME", "\x07\xe8\x01\x02\x03\x04\x05")), dropped: []string{"tIME"}, wantSize: len(clean)},
+		{name: "physical size", data: withPNGChunks(clean, physical), wantSize: len(clean) + len(physical)},
+		{name: "ZIP after IEND", data: append(append([]byte{}, clean...), "PK\x03\x04payload"...), want: "after its end"},
+		{name: "PHP in text", data: withPNGChunks(clean, pngChunk("tEXt", "Comment\x00<?php system($_GET[0]); ?>")), want: "embedded PHP"},
+		{name: "corrupt chunk", data: withPNGChunks(clean, corrupt), want: "corrupt chunk tEXt"},
+		{name: "missing IHDR", data: append(append([]byte{}, pngSignature...), clean[8+25:]...), want: "missing IHDR"},
+		{name: "truncated", data: clean[:len(clean)-6], want: "truncated file"},
+		{name: "chunk past the end", data: clean[:len(clean)-2], want: "invalid chunk length"},
+	}
+
+	for _, tt := range tests {
+		out, err := sanitizePNG(tt.data)
+		if tt.want != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		if len(out) != tt.wantSize {
+			t.Errorf("%s: got %d bytes, want %d", tt.name, len(out), tt.wantSize)
+		}
+		for _, s := range tt.dropped {
+			if bytes.Contains(out, []byte(s)) {
+				t.Errorf("%s: %q was kept", tt.name, s)
+			}
+		}
+		if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+			t.Errorf("%s: sanitized image does not decode: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PharmaKart/gateway-svc/pkg/utils"
+)
+
+// ErrInvalidReceipt is returned for receipts that are forged, expired, already
+// redeemed, or were issued to another user or for another purpose.
+var ErrInvalidReceipt = errors.New("invalid or expired upload receipt")
+
+// Receipts issues and redeems upload receipts: signed, expiring proof that a
+// user uploaded a file directly to storage and that it passed validation.
+// Handlers accept a receipt in place of the file itself, once.
+type Receipts struct {
+	signingKey []byte
+	ttl        time.Duration
+
+	mu sync.Mutex
+	// redeemed holds the IDs of redeemed receipts until they expire.
+	redeemed map[string]int64
+}
+
+type receipt struct {
+	ID        string `json:"id"`
+	Key       string `json:"key"`
+	Purpose   string `json:"purpose"`
+	UserID    string `json:"user_id"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewReceipts signs receipts with signingKey, valid for ttl. Without a key a
+// random one is used, so receipts are only accepted by this process.
+func NewReceipts(signingKey string, ttl time.Duration) *Receipts {
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		utils.Warn("UPLOAD_SIGNING_KEY is not set; upload receipts are only accepted by this instance", nil)
+	}
+	return &Receipts{signingKey: key, ttl: ttl, redeemed: make(map[string]int64)}
+}
+
+// Issue returns a receipt for the object at key, uploaded by userID for
+// purpose, and when it expires.
+func (r *Receipts) Issue(key, purpose, userID string) (string, time.Time) {
+	expiresAt := time.Now().Add(r.ttl)
+	payload, _ := json.Marshal(receipt{
+		ID:        utils.NewRequestID(),
+		Key:       key,
+		Purpose:   purpose,
+		UserID:    userID,
+		ExpiresAt: expiresAt.Unix(),
+	})
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + r.sign(encoded), expiresAt
+}
+
+// Open returns the object key of a receipt issued to userID for purpose,
+// without redeeming it.
+func (r *Receipts) Open(token, purpose, userID string) (string, error) {
+	rcpt, err := r.open(token, purpose, userID)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.redeemed[rcpt.ID]; ok {
+		return "", ErrInvalidReceipt
+	}
+	return rcpt.Key, nil
+}
+
+// Redeem returns the object key of a receipt issued to userID for purpose.
+// A receipt can be redeemed once, unless it is released; redemptions are
+// only tracked by this process, so the object should not outlive its first
+// use.
+func (r *Receipts) Redeem(token, purpose, userID string) (string, error) {
+	rcpt, err := r.open(token, purpose, userID)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Expired receipts are refused anyway, so their IDs can be forgotten
+	now := time.Now().Unix()
+	for id, expiresAt := range r.redeemed {
+		if now > expiresAt {
+			delete(r.redeemed, id)
+		}
+	}
+	if _, ok := r.redeemed[rcpt.ID]; ok {
+		return "", ErrInvalidReceipt
+	}
+	r.redeemed[rcpt.ID] = rcpt.ExpiresAt
+
+	return rcpt.Key, nil
+}
+
+// Release makes a redeemed receipt redeemable again, for when the request it
+// was redeemed for failed.
+func (r *Receipts) Release(token string) {
+	rcpt, err := r.decode(token)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	delete(r.redeemed, rcpt.ID)
+	r.mu.Unlock()
+}
+
+// open checks that token is a valid receipt issued to userID for purpose.
+func (r *Receipts) open(token, purpose, userID string) (*receipt, error) {
+	rcpt, err := r.decode(token)
+	if err != nil {
+		return nil, err
+	}
+	if rcpt.ID == "" || rcpt.Purpose != purpose || rcpt.UserID != userID || time.Now().Unix() > rcpt.ExpiresAt {
+		return nil, ErrInvalidReceipt
+	}
+	return rcpt, nil
+}
+
+// decode checks the signature of token and returns its receipt.
+func (r *Receipts) decode(token string) (*receipt, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(r.sign(encoded))) {
+		return nil, ErrInvalidReceipt
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidReceipt
+	}
+	var rcpt receipt
+	if err := json.Unmarshal(payload, &rcpt); err != nil {
+		return nil, ErrInvalidReceipt
+	}
+	return &rcpt, nil
+}
+
+func (r *Receipts) sign(encoded string) string {
+	mac := hmac.New(sha256.New, r.signingKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
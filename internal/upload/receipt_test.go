@@ -0,0 +1,83 @@
+package upload
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReceiptsRedeem(t *testing.T) {
+	receipts := NewReceipts("receipt-test", time.Minute)
+	token, _ := receipts.Issue("uploads/confirmed/prescriptions/a.pdf", "prescriptions", "u1")
+
+	for _, tt := range []struct{ name, purpose, userID string }{
+		{"other purpose", "products", "u1"},
+		{"other user", "prescriptions", "u2"},
+	} {
+		if _, err := receipts.Redeem(token, tt.purpose, tt.userID); !errors.Is(err, ErrInvalidReceipt) {
+			t.Errorf("%s: got error %v, want ErrInvalidReceipt", tt.name, err)
+		}
+	}
+
+	key, err := receipts.Redeem(token, "prescriptions", "u1")
+	if err != nil || key != "uploads/confirmed/prescriptions/a.pdf" {
+		t.Fatalf("got %q, %v, want the object key", key, err)
+	}
+	if _, err := receipts.Redeem(token, "prescriptions", "u1"); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("reused receipt: got error %v, want ErrInvalidReceipt", err)
+	}
+
+	// Receipts for the same object are redeemed independently
+	again, _ := receipts.Issue("uploads/confirmed/prescriptions/a.pdf", "prescriptions", "u1")
+	if _, err := receipts.Redeem(again, "prescriptions", "u1"); err != nil {
+		t.Errorf("second receipt: unexpected error: %v", err)
+	}
+}
+
+func TestReceiptsRedeemInvalid(t *testing.T) {
+	receipts := NewReceipts("receipt-test", time.Minute)
+	token, _ := receipts.Issue("key", "products", "u1")
+
+	expired := NewReceipts("receipt-test", -time.Minute)
+	expiredToken, _ := expired.Issue("key", "products", "u1")
+
+	forged, _ := NewReceipts("other-key", time.Minute).Issue("key", "products", "u1")
+
+	for name, token := range map[string]string{
+		"expired":   expiredToken,
+		"forged":    forged,
+		"truncated": token[:len(token)-2],
+		"malformed": "not-a-receipt",
+	} {
+		if _, err := receipts.Redeem(token, "products", "u1"); !errors.Is(err, ErrInvalidReceipt) {
+			t.Errorf("%s: got error %v, want ErrInvalidReceipt", name, err)
+		}
+	}
+}
+
+func TestReceiptsOpenAndRelease(t *testing.T) {
+	receipts := NewReceipts("receipt-test", time.Minute)
+	token, _ := receipts.Issue("key", "products", "u1")
+
+	// Opening does not use the receipt up
+	for i := 0; i < 2; i++ {
+		if key, err := receipts.Open(token, "products", "u1"); err != nil || key != "key" {
+			t.Fatalf("open %d: got %q, %v, want the object key", i, key, err)
+		}
+	}
+	if _, err := receipts.Open(token, "products", "u2"); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("other user: got error %v, want ErrInvalidReceipt", err)
+	}
+
+	if _, err := receipts.Redeem(token, "products", "u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := receipts.Open(token, "products", "u1"); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("redeemed receipt: got error %v, want ErrInvalidReceipt", err)
+	}
+
+	receipts.Release(token)
+	if _, err := receipts.Redeem(token, "products", "u1"); err != nil {
+		t.Errorf("released receipt: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+// Package upload validates uploaded files before they are stored: it enforces
+// size limits, detects the real file type from its content, rejects files
+// that pass for more than one format, and strips metadata such as EXIF and
+// GPS positions from images.
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/PharmaKart/gateway-svc/pkg/config"
+)
+
+// Supported file types.
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypePDF  = "application/pdf"
+)
+
+// fileType describes how a supported type is recognized and cleaned.
+type fileType struct {
+	name       string
+	ext        string
+	extensions []string
+	mimeTypes  []string
+	magic      []byte
+	// sanitize returns the file without metadata, or an error if it is
+	// malformed or carries another format.
+	sanitize func(data []byte) ([]byte, error)
+}
+
+var fileTypes = map[string]fileType{
+	TypeJPEG: {
+		name:       "JPEG",
+		ext:        ".jpg",
+		extensions: []string{".jpg", ".jpeg", ".jpe", ".jfif"},
+		mimeTypes:  []string{TypeJPEG, "image/jpg", "image/pjpeg"},
+		magic:      []byte{0xFF, 0xD8, 0xFF},
+		sanitize:   sanitizeJPEG,
+	},
+	TypePNG: {
+		name:       "PNG",
+		ext:        ".png",
+		extensions: []string{".png"},
+		mimeTypes:  []string{TypePNG, "image/x-png"},
+		magic:      pngSignature,
+		sanitize:   sanitizePNG,
+	},
+	TypePDF: {
+		name:       "PDF",
+		ext:        ".pdf",
+		extensions: []string{".pdf"},
+		mimeTypes:  []string{TypePDF},
+		magic:      []byte("%PDF-"),
+		sanitize:   sanitizePDF,
+	},
+}
+
+// Policy is what a kind of upload may contain.
+type Policy struct {
+	MaxSize int64
+	Types   []string
+}
+
+// ProductImagePolicy accepts JPEG and PNG product images.
+func ProductImagePolicy(cfg config.UploadConfig) Policy {
+	return Policy{MaxSize: cfg.MaxImageSize, Types: []string{TypeJPEG, TypePNG}}
+}
+
+// PrescriptionPolicy accepts prescriptions as JPEG, PNG or PDF.
+func PrescriptionPolicy(cfg config.UploadConfig) Policy {
+	return Policy{MaxSize: cfg.MaxPrescriptionSize, Types: []string{TypeJPEG, TypePNG, TypePDF}}
+}
+
+// Allows reports whether files of contentType may be uploaded, and returns
+// the canonical name of the type, e.g. image/jpeg for image/jpg.
+func (p Policy) Allows(contentType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	for _, allowed := range p.Types {
+		if contains(fileTypes[allowed].mimeTypes, mediaType) {
+			return allowed, true
+		}
+	}
+	return "", false
+}
+
+// Extension returns the canonical extension of a supported type.
+func Extension(contentType string) string {
+	return fileTypes[contentType].ext
+}
+
+// File is a validated upload, ready to be stored.
+type File struct {
+	Data        []byte
+	ContentType string
+	// Ext is the canonical extension for ContentType, e.g. ".jpg".
+	Ext string
+}
+
+// Reader returns a reader over the file's content.
+func (f *File) Reader() io.Reader {
+	return bytes.NewReader(f.Data)
+}
+
+// Size is the file's size after sanitizing.
+func (f *File) Size() int64 {
+	return int64(len(f.Data))
+}
+
+// ValidationError explains why an upload in a form field was refused.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// Details returns the error as VALIDATION_ERROR details.
+func (e *ValidationError) Details() map[string]string {
+	return map[string]string{e.Field: e.Reason}
+}
+
+// rejection is returned by sanitizers for files that are readable but not
+// acceptable, and is reported as is.
+type rejection string
+
+func (r rejection) Error() string {
+	return string(r)
+}
+
+func reject(format string, args ...interface{}) error {
+	return rejection(fmt.Sprintf(format, args...))
+}
+
+// Validate reads the file uploaded in field and checks it against the
+// policy. Refused files are reported as a *ValidationError.
+func Validate(field string, header *multipart.FileHeader, policy Policy) (*File, error) {
+	if header.Size > policy.MaxSize {
+		return nil, TooLarge(field, policy)
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, policy.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	return Check(field, header.Filename, header.Header.Get("Content-Type"), data, policy)
+}
+
+// Check validates the content of a file uploaded in field with its original
+// filename and declared content type, either of which may be empty.
+func Check(field, filename, declaredType string, data []byte, policy Policy) (*File, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if int64(len(data)) > policy.MaxSize {
+		return nil, TooLarge(field, policy)
+	}
+	if len(data) == 0 {
+		return nil, invalid("File is empty")
+	}
+
+	ft, ok := detect(data, policy.Types)
+	if !ok {
+		return nil, invalid("File content is not one of the allowed types (%s)", allowedNames(policy.Types))
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != "" && !contains(ft.extensions, ext) {
+		return nil, invalid("File extension %s does not match its %s content", ext, ft.name)
+	}
+
+	if declaredType != "" {
+		mediaType, _, err := mime.ParseMediaType(declaredType)
+		if err == nil && mediaType != "application/octet-stream" && !contains(ft.mimeTypes, mediaType) {
+			return nil, invalid("Declared content type %s does not match its %s content", mediaType, ft.name)
+		}
+	}
+
+	clean, err := ft.sanitize(data)
+	var rejected rejection
+	if errors.As(err, &rejected) {
+		return nil, invalid("%s", rejected)
+	}
+	if err != nil {
+		return nil, invalid("File is not a valid %s: %v", ft.name, err)
+	}
+
+	return &File{Data: clean, ContentType: ft.mimeTypes[0], Ext: ft.ext}, nil
+}
+
+// detect returns the allowed type whose signature starts the data.
+func detect(data []byte, allowed []string) (fileType, bool) {
+	for _, mimeType := range allowed {
+		ft := fileTypes[mimeType]
+		if bytes.HasPrefix(data, ft.magic) {
+			return ft, true
+		}
+	}
+	return fileType{}, false
+}
+
+// foreignSignatures mark content of another format: documents, archives
+// and markup a browser or interpreter might run.
+var foreignSignatures = []struct {
+	signature []byte
+	name      string
+}{
+	{[]byte("%pdf-"), "PDF"},
+	{[]byte("pk\x03\x04"), "ZIP"},
+	{[]byte("<?php"), "PHP"},
+	{[]byte("<script"), "script"},
+	{[]byte("<html"), "HTML"},
+	{[]byte("<!doctype"), "HTML"},
+	{[]byte("<svg"), "SVG"},
+	{[]byte("<iframe"), "HTML"},
+}
+
+// checkForeign rejects data that carries another format. It is only run on
+// the parts of a file that are not compressed image data.
+func checkForeign(data []byte) error {
+	lower := bytes.ToLower(data)
+	for _, foreign := range foreignSignatures {
+		if bytes.Contains(lower, foreign.signature) {
+			return reject("File contains embedded %s content", foreign.name)
+		}
+	}
+	return nil
+}
+
+// checkTrailing rejects data appended after the end of a file, the usual
+// way of making it pass for two formats. Zero padding is allowed.
+func checkTrailing(trailing []byte) error {
+	if len(bytes.Trim(trailing, "\x00")) > 0 {
+		return reject("File has %d bytes of data after its end", len(trailing))
+	}
+	return nil
+}
+
+func allowedNames(types []string) string {
+	names := make([]string, 0, len(types))
+	for _, mimeType := range types {
+		names = append(names, fileTypes[mimeType].name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// TooLarge is the error for a file in field over the policy's size limit.
+func TooLarge(field string, policy Policy) *ValidationError {
+	return &ValidationError{Field: field, Reason: "File is larger than the limit of " + formatSize(policy.MaxSize)}
+}
+
+func formatSize(size int64) string {
+	if size >= 1<<20 && size%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", size>>20)
+	}
+	if size >= 1<<10 && size%(1<<10) == 0 {
+		return fmt.Sprintf("%d KB", size>>10)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	jpg := testJPEG(t)
+	png := testPNG(t)
+	pdf := []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	images := Policy{MaxSize: 1 << 20, Types: []string{TypeJPEG, TypePNG}}
+
+	tests := []struct {
+		name         string
+		filename     string
+		declaredType string
+		data         []byte
+		policy       Policy
+		want         string
+		wantType     string
+	}{
+		{name: "JPEG", filename: "photo.JPG", declaredType: "image/jpeg", data: jpg, policy: images, wantType: TypeJPEG},
+		{name: "PNG without a name or type", data: png, policy: images, wantType: TypePNG},
+		{name: "alternative MIME type", filename: "photo.jpeg", declaredType: "image/pjpeg", data: jpg, policy: images, wantType: TypeJPEG},
+		{name: "octet-stream", filename: "scan.png", declaredType: "application/octet-stream", data: png, policy: images, wantType: TypePNG},
+		{name: "PNG named .jpg", filename: "photo.jpg", declaredType: "image/jpeg", data: png, policy: images, want: "extension .jpg does not match its PNG content"},
+		{name: "PNG declared as JPEG", filename: "photo.png", declaredType: "image/jpeg", data: png, policy: images, want: "content type image/jpeg does not match its PNG content"},
+		{name: "JPEG declared as HTML", filename: "photo.jpg", declaredType: "text/html; charset=utf-8", data: jpg, policy: images, want: "content type text/html does not match"},
+		{name: "JPEG named .php", filename: "shell.php", data: jpg, policy: images, want: "extension .php does not match"},
+		{name: "PDF for an image", filename: "scan.pdf", declaredType: TypePDF, data: pdf, policy: images, want: "not one of the allowed types (JPEG, PNG)"},
+		{name: "empty", filename: "photo.png", data: nil, policy: images, want: "File is empty"},
+		{name: "too large", filename: "photo.png", data: png, policy: Policy{MaxSize: 16, Types: images.Types}, want: "larger than the limit of 16 bytes"},
+		{name: "ZIP after the image", filename: "photo.png", data: append(append([]byte{}, png...), "PK\x03\x04"...), policy: images, want: "after its end"},
+	}
+
+	for _, tt := range tests {
+		file, err := Check("image", tt.filename, tt.declaredType, tt.data, tt.policy)
+		if tt.want != "" {
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != "image" || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: got error %v, want a validation error %q", tt.name, err, tt.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if file.ContentType != tt.wantType || file.Ext != fileTypes[tt.wantType].ext || !bytes.HasPrefix(file.Data, fileTypes[tt.wantType].magic) {
+			t.Errorf("%s: got %s %s, want %s", tt.name, file.ContentType, file.Ext, tt.wantType)
+		}
+	}
+}
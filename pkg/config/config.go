@@ -3,20 +3,264 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// TLSConfig holds the transport security settings for a backend connection.
+// A client certificate and key enable mutual TLS.
+type TLSConfig struct {
+	Enabled        bool
+	CAFile         string
+	CertFile       string
+	KeyFile        string
+	ServerName     string
+	ReloadInterval time.Duration
+}
+
+// RPCPolicyConfig controls deadlines, retries and hedging for backend calls.
+// Timeouts are looked up by method (short "GetProduct" or full
+// "/product.ProductService/GetProduct" name), then by service, then Timeout.
+// Only methods listed in IdempotentMethods are ever retried or hedged.
+type RPCPolicyConfig struct {
+	Timeout             time.Duration
+	ServiceTimeouts     map[string]time.Duration
+	MethodTimeouts      map[string]time.Duration
+	IdempotentMethods   []string
+	RetryMaxAttempts    int
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+	RetryBudgetRatio    float64
+	RetryBudgetMin      int
+	HedgeDelay          time.Duration
+}
+
+// CircuitBreakerConfig controls when a backend's circuit breaker trips. The
+// circuit opens once at least MinRequests of the last WindowSize calls have
+// been recorded and either rate reaches its threshold.
+type CircuitBreakerConfig struct {
+	Enabled               bool
+	WindowSize            int
+	MinRequests           int
+	FailureRateThreshold  float64
+	SlowCallThreshold     time.Duration
+	SlowCallRateThreshold float64
+	OpenTimeout           time.Duration
+	HalfOpenProbes        int
+}
+
+// LoadBalancingConfig controls how calls are spread across the endpoints of
+// a backend pool and when a misbehaving endpoint is ejected from it.
+type LoadBalancingConfig struct {
+	Policy                     string
+	RefreshInterval            time.Duration
+	OutlierConsecutiveFailures int
+	OutlierBaseEjectionTime    time.Duration
+	OutlierMaxEjectionPercent  int
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported: "otlp",
+// "stdout" or "none". Pending spans get FlushTimeout to be exported on
+// shutdown.
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	ServiceName  string
+	SampleRatio  float64
+	FlushTimeout time.Duration
+}
+
+// LoggingConfig controls the log level and format, and which field names
+// are masked in log entries.
+type LoggingConfig struct {
+	Level        string
+	Pretty       bool
+	RedactFields []string
+}
+
+// AuditConfig selects where audit events are written: "file" for a local
+// hash-chained log or "http" for an external collector. FilePath defaults to
+// audit.log in development only; elsewhere it must point at a persistent
+// volume.
+type AuditConfig struct {
+	Sink             string
+	FilePath         string
+	CollectorURL     string
+	CollectorTimeout time.Duration
+}
+
+// TokenCacheConfig controls how long token verification results are reused.
+// Valid tokens are cached for at most TTL and never past their expiry;
+// rejected tokens are cached for NegativeTTL. Rejected tokens count against
+// MaxNegativeEntries instead of MaxEntries, so a flood of bad tokens cannot
+// evict valid sessions.
+type TokenCacheConfig struct {
+	Enabled            bool
+	TTL                time.Duration
+	NegativeTTL        time.Duration
+	MaxEntries         int
+	MaxNegativeEntries int
+}
+
+// JWTConfig enables local verification of signed JWTs with keys from a JWKS
+// endpoint or a key file (JWKS or PEM). Tokens that are not JWTs are still
+// verified by the auth service.
+type JWTConfig struct {
+	Enabled         bool
+	JWKSURL         string
+	KeyFile         string
+	Issuer          string
+	Audience        string
+	Leeway          time.Duration
+	RefreshInterval time.Duration
+}
+
+// RevocationConfig controls how often the list of revoked tokens is pulled
+// from the auth service, and how long a revocation is kept when the auth
+// service does not say when the revoked tokens expire.
+type RevocationConfig struct {
+	SyncInterval time.Duration
+	Retention    time.Duration
+}
+
+// NotificationConfig selects how emails are delivered: "smtp", "file" (one
+// JSON line per message) or "log" (nothing is delivered). LinkBaseURL is the
+// frontend URL that verification and reset links point to.
+type NotificationConfig struct {
+	Sender       string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	FilePath     string
+	LinkBaseURL  string
+}
+
+// AccountRateLimitConfig limits verification and password reset requests.
+type AccountRateLimitConfig struct {
+	PerEmail int
+	PerIP    int
+	Window   time.Duration
+}
+
+// MFAConfig lists the roles whose sessions must have passed a second factor,
+// and limits attempts to complete an MFA login per client IP and per
+// challenge token.
+type MFAConfig struct {
+	RequiredRoles    []string
+	AttemptsPerIP    int
+	AttemptsPerToken int
+	AttemptWindow    time.Duration
+}
+
+// RBACConfig points at the role to permission policy. Without a file the
+// built-in policy is used.
+type RBACConfig struct {
+	PolicyFile     string
+	ReloadInterval time.Duration
+}
+
+// PrescriptionStorageConfig stores prescriptions privately, in their own
+// bucket, with server-side encryption ("AES256", or "aws:kms" with an
+// optional KMS key). They are only handed out through presigned URLs valid
+// for URLTTL. LegacyURLs resolves the public URLs of orders placed before
+// prescriptions were kept privately, once their files have been moved.
+type PrescriptionStorageConfig struct {
+	Bucket               string
+	ServerSideEncryption string
+	KMSKeyID             string
+	URLTTL               time.Duration
+	LegacyURLs           bool
+}
+
+// StorageConfig selects where uploads are kept: "s3" (AWS), "s3compatible"
+// (Endpoint, usually with PathStyle, e.g. MinIO) or "local" (LocalDir, served
+// by the gateway at LocalURL). PublicURL overrides the address public objects
+// are linked at, e.g. for a CDN.
+type StorageConfig struct {
+	Driver          string
+	Region          string
+	Endpoint        string
+	PathStyle       bool
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string
+	LocalDir        string
+	LocalURL        string
+	SigningKey      string
+}
+
+// UploadConfig limits the size of uploaded files, in bytes. Files uploaded
+// directly to storage must be posted within PresignTTL, and the receipt for a
+// confirmed upload is valid for ReceiptTTL.
+type UploadConfig struct {
+	MaxImageSize        int64
+	MaxPrescriptionSize int64
+	PresignTTL          time.Duration
+	ReceiptTTL          time.Duration
+	SigningKey          string
+}
+
+// ScanConfig selects the malware scanner for uploads: "clamd" (a ClamAV
+// daemon at ClamdAddress, "tcp://host:port" or "unix:///path") or "none".
+// Flagged files are kept in QuarantineBucket, a bucket of their own that
+// clamd requires. With FailOpen, uploads are stored unscanned when the scanner is unavailable instead of being refused.
+type ScanConfig struct {
+	Driver           string
+	ClamdAddress     string
+	Timeout          time.Duration
+	FailOpen         bool
+	QuarantineBucket string
+}
+
 type Config struct {
+	Environment         string
 	Port                string
 	AuthServiceURL      string
 	ProductServiceURL   string
 	OrderServiceURL     string
 	PaymentServiceURL   string
 	ReminderServiceURL  string
+	AuthServiceTLS      TLSConfig
+	ProductServiceTLS   TLSConfig
+	OrderServiceTLS     TLSConfig
+	PaymentServiceTLS   TLSConfig
+	ReminderServiceTLS  TLSConfig
+	AuthServiceLB       LoadBalancingConfig
+	ProductServiceLB    LoadBalancingConfig
+	OrderServiceLB      LoadBalancingConfig
+	PaymentServiceLB    LoadBalancingConfig
+	ReminderServiceLB   LoadBalancingConfig
+	RPCPolicy           RPCPolicyConfig
+	CircuitBreaker      CircuitBreakerConfig
+	Tracing             TracingConfig
+	Logging             LoggingConfig
+	Audit               AuditConfig
+	TokenCache          TokenCacheConfig
+	JWT                 JWTConfig
+	Revocation          RevocationConfig
+	Notification        NotificationConfig
+	AccountRateLimit    AccountRateLimitConfig
+	MFA                 MFAConfig
+	RBAC                RBACConfig
 	StripeWebhookSecret string
 	S3Bucket            string
-	AwsRegion           string
+	Storage             StorageConfig
+	Prescriptions       PrescriptionStorageConfig
+	Upload              UploadConfig
+	Scan                ScanConfig
+	ShutdownDelay       time.Duration
+	ShutdownTimeout     time.Duration
+	ReadinessTimeout    time.Duration
+	CriticalServices    []string
+	// TrustedProxies are the addresses whose X-Forwarded-For is believed
+	// when resolving the client IP. Without any, the peer address is used.
+	TrustedProxies []string
 }
 
 func LoadConfig() *Config {
@@ -25,16 +269,49 @@ func LoadConfig() *Config {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	environment := getEnv("APP_ENV", "development")
+
 	return &Config{
+		Environment:         environment,
 		Port:                getEnv("PORT", "8080"),
 		AuthServiceURL:      getEnv("AUTH_SERVICE_URL", "localhost:50051"),
 		ProductServiceURL:   getEnv("PRODUCT_SERVICE_URL", "localhost:50052"),
 		OrderServiceURL:     getEnv("ORDER_SERVICE_URL", "localhost:50053"),
 		PaymentServiceURL:   getEnv("PAYMENT_SERVICE_URL", "localhost:50054"),
 		ReminderServiceURL:  getEnv("REMINDER_SERVICE_URL", "localhost:50055"),
+		AuthServiceTLS:      getTLSConfig("AUTH_SERVICE"),
+		ProductServiceTLS:   getTLSConfig("PRODUCT_SERVICE"),
+		OrderServiceTLS:     getTLSConfig("ORDER_SERVICE"),
+		PaymentServiceTLS:   getTLSConfig("PAYMENT_SERVICE"),
+		ReminderServiceTLS:  getTLSConfig("REMINDER_SERVICE"),
+		AuthServiceLB:       getLoadBalancingConfig("AUTH_SERVICE"),
+		ProductServiceLB:    getLoadBalancingConfig("PRODUCT_SERVICE"),
+		OrderServiceLB:      getLoadBalancingConfig("ORDER_SERVICE"),
+		PaymentServiceLB:    getLoadBalancingConfig("PAYMENT_SERVICE"),
+		ReminderServiceLB:   getLoadBalancingConfig("REMINDER_SERVICE"),
+		RPCPolicy:           getRPCPolicyConfig(),
+		CircuitBreaker:      getCircuitBreakerConfig(),
+		Tracing:             getTracingConfig(),
+		Logging:             getLoggingConfig(environment),
+		Audit:               getAuditConfig(environment),
+		TokenCache:          getTokenCacheConfig(),
+		JWT:                 getJWTConfig(),
+		Revocation:          getRevocationConfig(),
+		Notification:        getNotificationConfig(),
+		AccountRateLimit:    getAccountRateLimitConfig(),
+		MFA:                 getMFAConfig(),
+		RBAC:                getRBACConfig(),
 		StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", "whsec_your_stripe_webhook_secret"),
 		S3Bucket:            getEnv("S3_BUCKET_NAME", "your_s3_bucket"),
-		AwsRegion:           getEnv("AWS_REGION", "ca-central-1"),
+		Storage:             getStorageConfig(),
+		Prescriptions:       getPrescriptionStorageConfig(),
+		Upload:              getUploadConfig(),
+		Scan:                getScanConfig(),
+		ShutdownDelay:       getEnvDuration("SHUTDOWN_DELAY", 5*time.Second),
+		ShutdownTimeout:     getEnvDuration("SHUTDOWN_TIMEOUT", 25*time.Second),
+		ReadinessTimeout:    getEnvDuration("READINESS_TIMEOUT", 2*time.Second),
+		CriticalServices:    getEnvList("CRITICAL_SERVICES", []string{"auth", "product", "order"}),
+		TrustedProxies:      getEnvList("TRUSTED_PROXIES", nil),
 	}
 }
 
@@ -45,3 +322,277 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getTLSConfig reads the TLS settings for a backend service. Per-service
+// variables (e.g. AUTH_SERVICE_TLS_CA_FILE) fall back to the shared GRPC_TLS_*
+// ones so a single CA bundle can be configured for every backend.
+func getTLSConfig(prefix string) TLSConfig {
+	return TLSConfig{
+		Enabled:        getEnvBool(prefix+"_TLS_ENABLED", getEnvBool("GRPC_TLS_ENABLED", false)),
+		CAFile:         getEnv(prefix+"_TLS_CA_FILE", os.Getenv("GRPC_TLS_CA_FILE")),
+		CertFile:       getEnv(prefix+"_TLS_CERT_FILE", os.Getenv("GRPC_TLS_CERT_FILE")),
+		KeyFile:        getEnv(prefix+"_TLS_KEY_FILE", os.Getenv("GRPC_TLS_KEY_FILE")),
+		ServerName:     os.Getenv(prefix + "_TLS_SERVER_NAME"),
+		ReloadInterval: getEnvDuration("GRPC_TLS_RELOAD_INTERVAL", time.Minute),
+	}
+}
+
+// getLoadBalancingConfig reads the load balancing settings for a backend
+// service, falling back to the shared GRPC_LB_* variables.
+func getLoadBalancingConfig(prefix string) LoadBalancingConfig {
+	return LoadBalancingConfig{
+		Policy:                     getEnv(prefix+"_LB_POLICY", getEnv("GRPC_LB_POLICY", "round_robin")),
+		RefreshInterval:            getEnvDuration("GRPC_LB_REFRESH_INTERVAL", 30*time.Second),
+		OutlierConsecutiveFailures: getEnvInt("GRPC_LB_OUTLIER_CONSECUTIVE_FAILURES", 5),
+		OutlierBaseEjectionTime:    getEnvDuration("GRPC_LB_OUTLIER_BASE_EJECTION_TIME", 30*time.Second),
+		OutlierMaxEjectionPercent:  getEnvInt("GRPC_LB_OUTLIER_MAX_EJECTION_PERCENT", 50),
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDurationMap parses a comma-separated list of key=duration pairs,
+// e.g. "payment=3s,product=2s". Malformed entries are skipped.
+func getEnvDurationMap(key string) map[string]time.Duration {
+	values := make(map[string]time.Duration)
+	for _, item := range getEnvList(key, nil) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(k)] = d
+	}
+	return values
+}
+
+func getRPCPolicyConfig() RPCPolicyConfig {
+	return RPCPolicyConfig{
+		Timeout:         getEnvDuration("GRPC_TIMEOUT", 5*time.Second),
+		ServiceTimeouts: getEnvDurationMap("GRPC_SERVICE_TIMEOUTS"),
+		MethodTimeouts:  getEnvDurationMap("GRPC_METHOD_TIMEOUTS"),
+		IdempotentMethods: getEnvList("GRPC_IDEMPOTENT_METHODS", []string{
+			"VerifyToken", "ListRevokedTokens",
+			"GetProduct", "ListProducts", "GetInventoryLogs",
+			"GetOrder", "ListCustomersOrders", "ListAllOrders",
+			"GetPayment", "GetPaymentByOrderID", "GetPaymentByTransactionID",
+			"ListReminders", "ListCustomerReminders", "ListReminderLogs",
+		}),
+		RetryMaxAttempts:    getEnvInt("GRPC_RETRY_MAX_ATTEMPTS", 3),
+		RetryInitialBackoff: getEnvDuration("GRPC_RETRY_INITIAL_BACKOFF", 50*time.Millisecond),
+		RetryMaxBackoff:     getEnvDuration("GRPC_RETRY_MAX_BACKOFF", time.Second),
+		RetryBudgetRatio:    getEnvFloat("GRPC_RETRY_BUDGET_RATIO", 0.1),
+		RetryBudgetMin:      getEnvInt("GRPC_RETRY_BUDGET_MIN_PER_SECOND", 10),
+		HedgeDelay:          getEnvDuration("GRPC_HEDGE_DELAY", 0),
+	}
+}
+
+func getCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Enabled:               getEnvBool("CIRCUIT_BREAKER_ENABLED", true),
+		WindowSize:            getEnvInt("CIRCUIT_BREAKER_WINDOW_SIZE", 50),
+		MinRequests:           getEnvInt("CIRCUIT_BREAKER_MIN_REQUESTS", 10),
+		FailureRateThreshold:  getEnvFloat("CIRCUIT_BREAKER_FAILURE_RATE", 0.5),
+		SlowCallThreshold:     getEnvDuration("CIRCUIT_BREAKER_SLOW_CALL_THRESHOLD", 2*time.Second),
+		SlowCallRateThreshold: getEnvFloat("CIRCUIT_BREAKER_SLOW_CALL_RATE", 0.8),
+		OpenTimeout:           getEnvDuration("CIRCUIT_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		HalfOpenProbes:        getEnvInt("CIRCUIT_BREAKER_HALF_OPEN_PROBES", 3),
+	}
+}
+
+func getTracingConfig() TracingConfig {
+	return TracingConfig{
+		Exporter:     strings.ToLower(getEnv("TRACING_EXPORTER", "none")),
+		OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317"),
+		OTLPInsecure: getEnvBool("OTEL_EXPORTER_OTLP_INSECURE", true),
+		ServiceName:  getEnv("OTEL_SERVICE_NAME", "gateway-svc"),
+		SampleRatio:  getEnvFloat("TRACING_SAMPLE_RATIO", 1.0),
+		FlushTimeout: getEnvDuration("TRACING_FLUSH_TIMEOUT", 5*time.Second),
+	}
+}
+
+func getLoggingConfig(environment string) LoggingConfig {
+	return LoggingConfig{
+		Level:  getEnv("LOG_LEVEL", "info"),
+		Pretty: getEnvBool("LOG_PRETTY", environment != "production"),
+		RedactFields: getEnvList("LOG_REDACT_FIELDS", []string{
+			"password", "email", "phone", "prescription_url", "token", "authorization",
+			"secret", "recovery_code", "provisioning_uri",
+		}),
+	}
+}
+
+func getAuditConfig(environment string) AuditConfig {
+	defaultPath := ""
+	if environment == "development" {
+		defaultPath = "audit.log"
+	}
+
+	return AuditConfig{
+		Sink:             strings.ToLower(getEnv("AUDIT_SINK", "file")),
+		FilePath:         getEnv("AUDIT_FILE_PATH", defaultPath),
+		CollectorURL:     getEnv("AUDIT_COLLECTOR_URL", ""),
+		CollectorTimeout: getEnvDuration("AUDIT_COLLECTOR_TIMEOUT", 5*time.Second),
+	}
+}
+
+func getTokenCacheConfig() TokenCacheConfig {
+	return TokenCacheConfig{
+		Enabled:            getEnvBool("TOKEN_CACHE_ENABLED", true),
+		TTL:                getEnvDuration("TOKEN_CACHE_TTL", time.Minute),
+		NegativeTTL:        getEnvDuration("TOKEN_CACHE_NEGATIVE_TTL", 10*time.Second),
+		MaxEntries:         getEnvInt("TOKEN_CACHE_MAX_ENTRIES", 10000),
+		MaxNegativeEntries: getEnvInt("TOKEN_CACHE_MAX_NEGATIVE_ENTRIES", 1000),
+	}
+}
+
+func getJWTConfig() JWTConfig {
+	return JWTConfig{
+		Enabled:         getEnvBool("JWT_LOCAL_VERIFICATION", false),
+		JWKSURL:         getEnv("JWT_JWKS_URL", ""),
+		KeyFile:         getEnv("JWT_KEY_FILE", ""),
+		Issuer:          getEnv("JWT_ISSUER", ""),
+		Audience:        getEnv("JWT_AUDIENCE", ""),
+		Leeway:          getEnvDuration("JWT_LEEWAY", 30*time.Second),
+		RefreshInterval: getEnvDuration("JWT_KEYS_REFRESH_INTERVAL", 5*time.Minute),
+	}
+}
+
+func getRevocationConfig() RevocationConfig {
+	return RevocationConfig{
+		SyncInterval: getEnvDuration("TOKEN_REVOCATION_SYNC_INTERVAL", 30*time.Second),
+		Retention:    getEnvDuration("TOKEN_REVOCATION_RETENTION", 24*time.Hour),
+	}
+}
+
+func getNotificationConfig() NotificationConfig {
+	return NotificationConfig{
+		Sender:       strings.ToLower(getEnv("NOTIFICATION_SENDER", "log")),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		From:         getEnv("NOTIFICATION_FROM", "PharmaKart <no-reply@pharmakart.local>"),
+		FilePath:     getEnv("NOTIFICATION_FILE_PATH", "notifications.log"),
+		LinkBaseURL:  getEnv("APP_BASE_URL", "http://localhost:3000"),
+	}
+}
+
+func getAccountRateLimitConfig() AccountRateLimitConfig {
+	return AccountRateLimitConfig{
+		PerEmail: getEnvInt("ACCOUNT_RATE_LIMIT_PER_EMAIL", 3),
+		PerIP:    getEnvInt("ACCOUNT_RATE_LIMIT_PER_IP", 20),
+		Window:   getEnvDuration("ACCOUNT_RATE_LIMIT_WINDOW", time.Hour),
+	}
+}
+
+func getMFAConfig() MFAConfig {
+	return MFAConfig{
+		RequiredRoles:    getEnvList("MFA_REQUIRED_ROLES", nil),
+		AttemptsPerIP:    getEnvInt("MFA_ATTEMPTS_PER_IP", 10),
+		AttemptsPerToken: getEnvInt("MFA_ATTEMPTS_PER_TOKEN", 5),
+		AttemptWindow:    getEnvDuration("MFA_ATTEMPT_WINDOW", 15*time.Minute),
+	}
+}
+
+func getRBACConfig() RBACConfig {
+	return RBACConfig{
+		PolicyFile:     getEnv("RBAC_POLICY_FILE", ""),
+		ReloadInterval: getEnvDuration("RBAC_POLICY_RELOAD_INTERVAL", 30*time.Second),
+	}
+}
+
+func getPrescriptionStorageConfig() PrescriptionStorageConfig {
+	return PrescriptionStorageConfig{
+		Bucket:               getEnv("PRESCRIPTION_BUCKET_NAME", ""),
+		ServerSideEncryption: getEnv("PRESCRIPTION_SSE", "AES256"),
+		KMSKeyID:             getEnv("PRESCRIPTION_KMS_KEY_ID", ""),
+		URLTTL:               getEnvDuration("PRESCRIPTION_URL_TTL", 5*time.Minute),
+		LegacyURLs:           getEnvBool("PRESCRIPTION_LEGACY_URLS", false),
+	}
+}
+
+func getStorageConfig() StorageConfig {
+	driver := getEnv("STORAGE_DRIVER", "s3")
+
+	return StorageConfig{
+		Driver:          driver,
+		Region:          getEnv("AWS_REGION", "ca-central-1"),
+		Endpoint:        getEnv("STORAGE_ENDPOINT", ""),
+		PathStyle:       getEnvBool("STORAGE_PATH_STYLE", driver == "s3compatible"),
+		AccessKeyID:     getEnv("STORAGE_ACCESS_KEY_ID", ""),
+		SecretAccessKey: getEnv("STORAGE_SECRET_ACCESS_KEY", ""),
+		PublicURL:       getEnv("STORAGE_PUBLIC_URL", ""),
+		LocalDir:        getEnv("STORAGE_LOCAL_DIR", "./data/blobs"),
+		LocalURL:        getEnv("STORAGE_LOCAL_URL", "http://localhost:8080"),
+		SigningKey:      getEnv("STORAGE_SIGNING_KEY", ""),
+	}
+}
+
+func getUploadConfig() UploadConfig {
+	return UploadConfig{
+		MaxImageSize:        int64(getEnvInt("UPLOAD_MAX_IMAGE_SIZE", 5<<20)),
+		MaxPrescriptionSize: int64(getEnvInt("UPLOAD_MAX_PRESCRIPTION_SIZE", 10<<20)),
+		PresignTTL:          getEnvDuration("UPLOAD_PRESIGN_TTL", 15*time.Minute),
+		ReceiptTTL:          getEnvDuration("UPLOAD_RECEIPT_TTL", time.Hour),
+		SigningKey:          getEnv("UPLOAD_SIGNING_KEY", getEnv("STORAGE_SIGNING_KEY", "")),
+	}
+}
+
+func getScanConfig() ScanConfig {
+	return ScanConfig{
+		Driver:           getEnv("SCAN_DRIVER", "none"),
+		ClamdAddress:     getEnv("CLAMD_ADDRESS", "tcp://localhost:3310"),
+		Timeout:          getEnvDuration("SCAN_TIMEOUT", 30*time.Second),
+		FailOpen:         getEnv("SCAN_FAIL_MODE", "closed") == "open",
+		QuarantineBucket: getEnv("SCAN_QUARANTINE_BUCKET", ""),
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ErrorCode is an entry in the gateway's error catalog. Code is the stable
+// identifier clients can switch on; it is returned as "type" in the legacy
+// error format and as "code" in problem+json responses.
+type ErrorCode struct {
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+}
+
+// ProblemTypeBasePath is the path under which the catalog is served. Problem
+// type URIs are resolved relative to the gateway's own origin.
+const ProblemTypeBasePath = "/problems/"
+
+var (
+	ErrValidation     = ErrorCode{Code: "VALIDATION_ERROR", Title: "Request validation failed", Status: http.StatusBadRequest}
+	ErrBadRequest     = ErrorCode{Code: "BAD_REQUEST_ERROR", Title: "Bad request", Status: http.StatusBadRequest}
+	ErrRequestFailed  = ErrorCode{Code: "REQUEST_FAILED", Title: "Request could not be completed", Status: http.StatusBadRequest}
+	ErrUnknown        = ErrorCode{Code: "UNKNOWN_ERROR", Title: "Unknown server error", Status: http.StatusInternalServerError}
+	ErrAuth           = ErrorCode{Code: "AUTH_ERROR", Title: "Authentication required", Status: http.StatusUnauthorized}
+	ErrForbidden      = ErrorCode{Code: "FORBIDDEN_ERROR", Title: "Access denied", Status: http.StatusForbidden}
+	ErrMFARequired    = ErrorCode{Code: "MFA_REQUIRED", Title: "Multi-factor authentication required", Status: http.StatusForbidden}
+	ErrNotFound       = ErrorCode{Code: "NOT_FOUND_ERROR", Title: "Resource not found", Status: http.StatusNotFound}
+	ErrConflict       = ErrorCode{Code: "CONFLICT_ERROR", Title: "Resource conflict", Status: http.StatusConflict}
+	ErrRateLimit      = ErrorCode{Code: "RATE_LIMIT_ERROR", Title: "Too many requests", Status: http.StatusTooManyRequests}
+	ErrCancelled      = ErrorCode{Code: "REQUEST_CANCELLED", Title: "Request cancelled", Status: 499}
+	ErrInternal       = ErrorCode{Code: "INTERNAL_ERROR", Title: "Internal server error", Status: http.StatusInternalServerError}
+	ErrNotImplemented = ErrorCode{Code: "NOT_IMPLEMENTED_ERROR", Title: "Not implemented", Status: http.StatusNotImplemented}
+	ErrUnavailable    = ErrorCode{Code: "SERVICE_UNAVAILABLE", Title: "Service temporarily unavailable", Status: http.StatusServiceUnavailable}
+	ErrTimeout        = ErrorCode{Code: "TIMEOUT_ERROR", Title: "Upstream service timed out", Status: http.StatusGatewayTimeout}
+)
+
+// ErrorCatalog lists every error the gateway can return.
+var ErrorCatalog = []ErrorCode{
+	ErrValidation,
+	ErrBadRequest,
+	ErrRequestFailed,
+	ErrUnknown,
+	ErrAuth,
+	ErrForbidden,
+	ErrMFARequired,
+	ErrNotFound,
+	ErrConflict,
+	ErrRateLimit,
+	ErrCancelled,
+	ErrInternal,
+	ErrNotImplemented,
+	ErrUnavailable,
+	ErrTimeout,
+}
+
+// TypeURI returns the problem type URI of the error, e.g.
+// "/problems/validation-error".
+func (e ErrorCode) TypeURI() string {
+	return ProblemTypeBasePath + strings.ReplaceAll(strings.ToLower(e.Code), "_", "-")
+}
+
+// LookupErrorCode finds a catalog entry by code or by its problem type slug.
+func LookupErrorCode(code string) (ErrorCode, bool) {
+	for _, e := range ErrorCatalog {
+		if e.Code == code || e.TypeURI() == ProblemTypeBasePath+code {
+			return e, true
+		}
+	}
+	return ErrorCode{}, false
+}
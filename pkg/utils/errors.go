@@ -1,16 +1,45 @@
 package utils
 
 import (
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/PharmaKart/gateway-svc/internal/proto"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const ProblemJSONContentType = "application/problem+json"
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Type    string            `json:"type"`
-	Message string            `json:"message"`
-	Details map[string]string `json:"details,omitempty"`
+	Type      string            `json:"type"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// ProblemDetails represents an RFC 7807 error response, returned to clients
+// that send "Accept: application/problem+json".
+type ProblemDetails struct {
+	Type     string       `json:"type" example:"/problems/validation-error"`
+	Title    string       `json:"title" example:"Request validation failed"`
+	Status   int          `json:"status" example:"400"`
+	Detail   string       `json:"detail,omitempty" example:"Invalid request format"`
+	Instance string       `json:"instance,omitempty" example:"urn:request:4f9c2a7e1b3d4c5e"`
+	Code     string       `json:"code" example:"VALIDATION_ERROR"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a problem with a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Convert proto error to HTTP response
@@ -30,20 +59,154 @@ func ConvertProtoErrorToResponse(protoError *proto.Error) (ErrorResponse, int) {
 
 	// Determine status code based on error type
 	statusCode := http.StatusInternalServerError
-	switch protoError.Type {
-	case "VALIDATION_ERROR":
-		statusCode = http.StatusBadRequest
-	case "AUTH_ERROR":
-		statusCode = http.StatusUnauthorized
-	case "NOT_FOUND_ERROR":
-		statusCode = http.StatusNotFound
-	case "BAD_REQUEST_ERROR":
-		statusCode = http.StatusBadRequest
-	case "CONFLICT_ERROR":
-		statusCode = http.StatusConflict
-	case "INTERNAL_ERROR":
-		statusCode = http.StatusInternalServerError
+	if errorCode, ok := LookupErrorCode(protoError.Type); ok {
+		statusCode = errorCode.Status
 	}
 
 	return errorResp, statusCode
 }
+
+// grpcErrorCodes maps gRPC status codes to the catalog entry returned to API
+// clients.
+var grpcErrorCodes = map[codes.Code]ErrorCode{
+	codes.Canceled:           ErrCancelled,
+	codes.InvalidArgument:    ErrValidation,
+	codes.FailedPrecondition: ErrBadRequest,
+	codes.OutOfRange:         ErrBadRequest,
+	codes.Unauthenticated:    ErrAuth,
+	codes.PermissionDenied:   ErrForbidden,
+	codes.NotFound:           ErrNotFound,
+	codes.AlreadyExists:      ErrConflict,
+	codes.Aborted:            ErrConflict,
+	codes.ResourceExhausted:  ErrRateLimit,
+	codes.Unimplemented:      ErrNotImplemented,
+	codes.Unavailable:        ErrUnavailable,
+	codes.DeadlineExceeded:   ErrTimeout,
+}
+
+// clientFacingCodes are the codes whose status message describes a problem
+// with the request itself and is safe to return to the client.
+var clientFacingCodes = map[codes.Code]bool{
+	codes.InvalidArgument:    true,
+	codes.FailedPrecondition: true,
+	codes.OutOfRange:         true,
+	codes.Unauthenticated:    true,
+	codes.PermissionDenied:   true,
+	codes.NotFound:           true,
+	codes.AlreadyExists:      true,
+}
+
+// Convert gRPC error to HTTP response. The fallback message is used whenever
+// the status message is not meant for clients, e.g. for internal errors.
+func ConvertGrpcErrorToResponse(err error, fallbackMessage string) (ErrorResponse, int) {
+	st := status.Convert(err)
+
+	errorCode, ok := grpcErrorCodes[st.Code()]
+	if !ok {
+		errorCode = ErrInternal
+	}
+
+	errorResp := ErrorResponse{
+		Type:    errorCode.Code,
+		Message: fallbackMessage,
+	}
+	if clientFacingCodes[st.Code()] && st.Message() != "" {
+		errorResp.Message = st.Message()
+	}
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				if errorResp.Details == nil {
+					errorResp.Details = make(map[string]string)
+				}
+				errorResp.Details[violation.GetField()] = violation.GetDescription()
+			}
+		case *errdetails.ErrorInfo:
+			if errorResp.Details == nil {
+				errorResp.Details = make(map[string]string)
+			}
+			errorResp.Details["reason"] = d.GetReason()
+		}
+	}
+
+	return errorResp, errorCode.Status
+}
+
+// RespondWithGrpcError writes the HTTP response for a failed gRPC call,
+// including a Retry-After header when the backend asked clients to back off.
+func RespondWithGrpcError(c *gin.Context, err error, fallbackMessage string) {
+	errorResp, statusCode := ConvertGrpcErrorToResponse(err, fallbackMessage)
+
+	for _, detail := range status.Convert(err).Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok && d.GetRetryDelay() != nil {
+			seconds := int(math.Ceil(d.GetRetryDelay().AsDuration().Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+		}
+	}
+
+	WriteError(c, statusCode, errorResp)
+}
+
+// RespondWithError writes the HTTP response for a catalog error.
+func RespondWithError(c *gin.Context, errorCode ErrorCode, message string, details map[string]string) {
+	WriteError(c, errorCode.Status, ErrorResponse{
+		Type:    errorCode.Code,
+		Message: message,
+		Details: details,
+	})
+}
+
+// WriteError writes an error response in the format the client asked for:
+// RFC 7807 problem details when the Accept header allows
+// application/problem+json, the legacy ErrorResponse otherwise.
+func WriteError(c *gin.Context, statusCode int, errorResp ErrorResponse) {
+	errorResp.RequestID = c.GetString(RequestIDKey)
+
+	if !acceptsProblemJSON(c) {
+		c.JSON(statusCode, errorResp)
+		return
+	}
+
+	c.Header("Content-Type", ProblemJSONContentType)
+	c.JSON(statusCode, NewProblemDetails(statusCode, errorResp))
+}
+
+// NewProblemDetails converts an ErrorResponse to RFC 7807 problem details.
+func NewProblemDetails(statusCode int, errorResp ErrorResponse) ProblemDetails {
+	problem := ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: errorResp.Message,
+		Code:   errorResp.Type,
+	}
+	// The instance identifies this occurrence of the problem by request ID
+	if errorResp.RequestID != "" {
+		problem.Instance = "urn:request:" + errorResp.RequestID
+	}
+	if errorCode, ok := LookupErrorCode(errorResp.Type); ok {
+		problem.Type = errorCode.TypeURI()
+		problem.Title = errorCode.Title
+	}
+
+	for field, message := range errorResp.Details {
+		problem.Errors = append(problem.Errors, FieldError{Field: field, Message: message})
+	}
+	sort.Slice(problem.Errors, func(i, j int) bool {
+		return problem.Errors[i].Field < problem.Errors[j].Field
+	})
+
+	return problem
+}
+
+func acceptsProblemJSON(c *gin.Context) bool {
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accept, ";")
+		if strings.TrimSpace(mediaType) == ProblemJSONContentType {
+			return true
+		}
+	}
+	return false
+}
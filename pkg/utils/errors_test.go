@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PharmaKart/gateway-svc/internal/proto"
+)
+
+func TestConvertProtoErrorToResponse(t *testing.T) {
+	tests := []struct {
+		errorType string
+		want      int
+	}{
+		{ErrValidation.Code, http.StatusBadRequest},
+		{ErrConflict.Code, http.StatusConflict},
+		{ErrRequestFailed.Code, http.StatusBadRequest},
+		{ErrUnknown.Code, http.StatusInternalServerError},
+		{"SOMETHING_NEW", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		resp, status := ConvertProtoErrorToResponse(&proto.Error{Type: tt.errorType, Message: "failed"})
+		if status != tt.want || resp.Type != tt.errorType {
+			t.Errorf("%s: got %s with status %d, want status %d", tt.errorType, resp.Type, status, tt.want)
+		}
+	}
+}
+
+func TestNewProblemDetails(t *testing.T) {
+	problem := NewProblemDetails(http.StatusBadRequest, ErrorResponse{
+		Type:      ErrValidation.Code,
+		Message:   "Invalid request format",
+		Details:   map[string]string{"items": "required", "address": "required"},
+		RequestID: "abc",
+	})
+
+	if problem.Type != ErrValidation.TypeURI() || problem.Title != ErrValidation.Title {
+		t.Errorf("got type %s titled %q", problem.Type, problem.Title)
+	}
+	if problem.Instance != "urn:request:abc" {
+		t.Errorf("got instance %q", problem.Instance)
+	}
+	if len(problem.Errors) != 2 || problem.Errors[0].Field != "address" {
+		t.Errorf("got errors %v, want them sorted by field", problem.Errors)
+	}
+}
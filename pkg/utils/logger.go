@@ -1,20 +1,58 @@
 package utils
 
 import (
+	"context"
+	"encoding/json"
 	"os"
+	"regexp"
+	"strings"
 
+	"github.com/PharmaKart/gateway-svc/pkg/config"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// RedactedValue replaces the value of every redacted log field.
+const RedactedValue = "[REDACTED]"
+
 var Logger *logrus.Logger
 
-func InitLogger() {
+// redaction masks the fields configured by InitLogger. It is also applied to
+// data recorded outside the log, such as audit events.
+var redaction redactionHook
+
+func InitLogger(cfg config.LoggingConfig) {
 	Logger = logrus.New()
-	Logger.SetLevel(logrus.DebugLevel)
+
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	Logger.SetLevel(level)
 	Logger.SetFormatter(&logrus.JSONFormatter{
-		PrettyPrint: true,
+		PrettyPrint: cfg.Pretty,
 	})
 	Logger.SetOutput(os.Stdout)
+	Logger.AddHook(requestIDHook{})
+	redaction = newRedactionHook(cfg.RedactFields)
+	Logger.AddHook(redaction)
+
+	if err != nil {
+		Warn("Unknown log level, using info", map[string]interface{}{
+			"level": cfg.Level,
+		})
+	}
+}
+
+// IsRedactedField reports whether values of the field are masked in logs.
+func IsRedactedField(key string) bool {
+	return redaction.sensitive(key)
+}
+
+// Redact returns value with its sensitive nested fields masked, as they
+// would be in a log entry.
+func Redact(value interface{}) interface{} {
+	return redaction.redactValue(value)
 }
 
 func Info(message string, fields map[string]interface{}) {
@@ -28,3 +66,161 @@ func Warn(message string, fields map[string]interface{}) {
 func Error(message string, fields map[string]interface{}) {
 	Logger.WithFields(fields).Error(message)
 }
+
+// InfoContext logs like Info, tagging the entry with the request ID in ctx.
+func InfoContext(ctx context.Context, message string, fields map[string]interface{}) {
+	Logger.WithContext(ctx).WithFields(fields).Info(message)
+}
+
+// WarnContext logs like Warn, tagging the entry with the request ID in ctx.
+func WarnContext(ctx context.Context, message string, fields map[string]interface{}) {
+	Logger.WithContext(ctx).WithFields(fields).Warn(message)
+}
+
+// ErrorContext logs like Error, tagging the entry with the request ID in ctx.
+func ErrorContext(ctx context.Context, message string, fields map[string]interface{}) {
+	Logger.WithContext(ctx).WithFields(fields).Error(message)
+}
+
+// requestIDHook adds the request ID and trace ID to every entry logged with
+// a context that carries them.
+type requestIDHook struct{}
+
+func (requestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (requestIDHook) Fire(entry *logrus.Entry) error {
+	if requestID := RequestIDFromContext(entry.Context); requestID != "" {
+		entry.Data[RequestIDKey] = requestID
+	}
+	if entry.Context != nil {
+		if spanContext := trace.SpanContextFromContext(entry.Context); spanContext.IsValid() {
+			entry.Data["trace_id"] = spanContext.TraceID().String()
+		}
+	}
+	return nil
+}
+
+// redactionHook masks sensitive fields in every log entry, including fields
+// nested inside logged structs and maps such as backend responses. A key is
+// sensitive when, ignoring case, underscores and dashes, it contains one of
+// the configured names, so "prescription_url" also masks "PrescriptionUrl".
+type redactionHook struct {
+	fields []string
+}
+
+func newRedactionHook(fields []string) redactionHook {
+	hook := redactionHook{}
+	for _, field := range fields {
+		if field = normalizeFieldName(field); field != "" {
+			hook.fields = append(hook.fields, field)
+		}
+	}
+	return hook
+}
+
+func (h redactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h redactionHook) Fire(entry *logrus.Entry) error {
+	for key, value := range entry.Data {
+		if h.sensitive(key) {
+			entry.Data[key] = RedactedValue
+			continue
+		}
+		entry.Data[key] = h.redactValue(value)
+	}
+	return nil
+}
+
+// redactValue returns value with its sensitive fields masked. Values that
+// can hold nested fields are round-tripped through JSON so they are walked
+// the same way the JSON formatter will print them.
+func (h redactionHook) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return value
+	case error:
+		return h.redactText(v.Error())
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return value
+	}
+	return h.redactDecoded(decoded)
+}
+
+func (h redactionHook) redactDecoded(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if h.sensitive(key) {
+				v[key] = RedactedValue
+				continue
+			}
+			v[key] = h.redactDecoded(nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = h.redactDecoded(nested)
+		}
+	}
+	return value
+}
+
+var (
+	// textFieldPattern matches key=value and key: value pairs, quoted or
+	// not, as backends and libraries write them into error messages.
+	textFieldPattern = regexp.MustCompile(`([A-Za-z][\w-]*)("?\s*[:=]\s*"?)([^\s,;&"'}\])]+)`)
+	emailPattern     = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+)
+
+// redactText masks the values of sensitive fields written into free text,
+// such as an error message, and email addresses when emails are redacted.
+func (h redactionHook) redactText(text string) string {
+	var out strings.Builder
+	for {
+		loc := textFieldPattern.FindStringSubmatchIndex(text)
+		if loc == nil {
+			out.WriteString(text)
+			break
+		}
+		if !h.sensitive(text[loc[2]:loc[3]]) {
+			// The value may itself hold a pair, as in "failed: token=x".
+			out.WriteString(text[:loc[5]])
+			text = text[loc[5]:]
+			continue
+		}
+		out.WriteString(text[:loc[5]])
+		out.WriteString(RedactedValue)
+		text = text[loc[1]:]
+	}
+	text = out.String()
+	if h.sensitive("email") {
+		text = emailPattern.ReplaceAllString(text, RedactedValue)
+	}
+	return text
+}
+
+func (h redactionHook) sensitive(key string) bool {
+	key = normalizeFieldName(key)
+	for _, field := range h.fields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeFieldName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "_", "")
+	return strings.ReplaceAll(name, "-", "")
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRedactValueMasksErrors(t *testing.T) {
+	hook := newRedactionHook([]string{"password", "email", "token"})
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("connection refused"), "connection refused"},
+		{errors.New("rpc error: code = NotFound desc = user jane.doe+rx@example.com not found"), "rpc error: code = NotFound desc = user [REDACTED] not found"},
+		{fmt.Errorf("login failed: password=hunter2, attempts=3"), "login failed: password=[REDACTED], attempts=3"},
+		{errors.New(`invalid request: {"refresh_token":"abc.def.ghi","user_id":"u1"}`), `invalid request: {"refresh_token":"[REDACTED]","user_id":"u1"}`},
+		{errors.New("bad header Token: xyz; retry"), "bad header Token: [REDACTED]; retry"},
+	}
+
+	for _, tt := range tests {
+		if got := hook.redactValue(tt.err); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+
+	// Email addresses are left alone when emails are not redacted
+	hook = newRedactionHook([]string{"password"})
+	if got := hook.redactValue(errors.New("user jane@example.com not found")); got != "user jane@example.com not found" {
+		t.Errorf("got %q, want the email kept", got)
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "gateway"
+
+// HTTP metrics are labelled by Gin route template, never by raw path, so
+// path parameters cannot blow up their cardinality.
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests served, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests, by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests currently being served, by route.",
+	}, []string{"method", "route"})
+)
+
+// gRPC client metrics count logical backend calls, so a call that succeeded
+// after a retry is recorded once.
+var (
+	GRPCClientRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "grpc_client_requests_total",
+		Help:      "Number of backend gRPC calls, by service, method and status code.",
+	}, []string{"service", "method", "code"})
+
+	GRPCClientRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "grpc_client_request_duration_seconds",
+		Help:      "Latency of backend gRPC calls, by service, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method", "code"})
+)
+
+var TokenCacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "token_cache_requests_total",
+	Help:      "Number of token verifications, by cache result (hit, negative_hit or miss).",
+}, []string{"result"})
+
+var WebhookEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "stripe_webhook_events_total",
+	Help:      "Number of verified Stripe webhook events received, by event type.",
+}, []string{"event_type", "handled"})
+
+var (
+	S3UploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "s3_upload_duration_seconds",
+		Help:      "Latency of blob store uploads, by folder and result.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"folder", "result"})
+
+	S3UploadSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "s3_upload_size_bytes",
+		Help:      "Size of files uploaded to the blob store, by folder.",
+		Buckets:   prometheus.ExponentialBuckets(16*1024, 4, 8),
+	}, []string{"folder"})
+)
+
+var (
+	UploadScansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upload_scans_total",
+		Help:      "Number of malware scans of uploads, by purpose and result (clean, infected or error).",
+	}, []string{"purpose", "result"})
+
+	UploadScanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "upload_scan_duration_seconds",
+		Help:      "Latency of malware scans of uploads, by purpose.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"purpose"})
+)
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most limit events per key in each fixed window.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow records an event for key. When the key is over its limit it returns
+// false and how long until the window resets.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.After(w.resetAt) {
+		if len(l.windows) > 10000 {
+			l.prune(now)
+		}
+		w = &rateWindow{resetAt: now.Add(l.window)}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.resetAt.Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// prune drops expired windows so the map does not grow without bound.
+func (l *RateLimiter) prune(now time.Time) {
+	for key, w := range l.windows {
+		if now.After(w.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDHeader is the HTTP header and, lowercased, the gRPC metadata key
+// that carries the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key the request ID is stored under.
+const RequestIDKey = "request_id"
+
+type requestIDContextKey struct{}
+
+// NewRequestID returns a random 128-bit request ID encoded as hex.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}